@@ -0,0 +1,525 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// apiTokenName is the file in the data directory holding the bearer token of
+// the local API. Scripts on the same machine read it from there.
+const apiTokenName = "api-token"
+
+// apiPrefix is the path all endpoints of the local API are under.
+const apiPrefix = "/api/v1"
+
+// maxAPIBodySize is the largest request body the local API reads.
+const maxAPIBodySize = maxImportFileSize
+
+//go:embed api/openapi.json
+var openAPIDocument []byte
+
+// APIStatus describes the local API for the settings screen.
+type APIStatus struct {
+	Enabled bool   `json:"enabled"`
+	Running bool   `json:"running"`
+	Address string `json:"address"`
+	Token   string `json:"token"`
+	Error   string `json:"error,omitempty"`
+}
+
+// apiJotRequest is the body of a JSON request creating or appending to a
+// jot. Content is given either as Markdown or as a TipTap document.
+type apiJotRequest struct {
+	Title    string `json:"title"`
+	Markdown string `json:"markdown"`
+	Content  *Node  `json:"content"`
+}
+
+// apiError is the body of an error response.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// LoadAPIToken returns the bearer token of the local API, creating a random
+// one the first time.
+func LoadAPIToken(dataDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, apiTokenName))
+	if err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("error reading API token: %w", err)
+	}
+	return NewAPIToken(dataDir)
+}
+
+// NewAPIToken replaces the bearer token of the local API with a new random
+// one. Clients using the old token are refused from then on.
+func NewAPIToken(dataDir string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("error generating API token: %w", err)
+	}
+	token := hex.EncodeToString(secret)
+	path := filepath.Join(dataDir, apiTokenName)
+	if err := writeFileAtomic(path, []byte(token+"\n")); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		return "", fmt.Errorf("error protecting API token: %w", err)
+	}
+	return token, nil
+}
+
+// apiHandler serves the REST endpoints of the local API.
+type apiHandler struct {
+	store *Store
+	token func() string
+	// onChange is called with every jot the API creates or changes.
+	onChange func(*Jot)
+	// onClip is called with every jot clipped from a web page.
+	onClip func(*Jot)
+}
+
+// NewAPIHandler returns the handler of the local API. Every endpoint except
+// the OpenAPI document requires the bearer token returned by token.
+func NewAPIHandler(store *Store, token func() string, onChange, onClip func(*Jot)) http.Handler {
+	h := &apiHandler{store: store, token: token, onChange: onChange, onClip: onClip}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+apiPrefix+"/openapi.json", h.openAPI)
+	mux.HandleFunc("GET "+apiPrefix+"/jots", h.auth(h.listJots))
+	mux.HandleFunc("POST "+apiPrefix+"/jots", h.auth(h.createJot))
+	mux.HandleFunc("GET "+apiPrefix+"/jots/{id}", h.auth(h.readJot))
+	mux.HandleFunc("POST "+apiPrefix+"/jots/{id}/append", h.auth(h.appendJot))
+	mux.HandleFunc("GET "+apiPrefix+"/search", h.auth(h.search))
+	mux.HandleFunc("OPTIONS "+apiPrefix+"/clips", h.clipPreflight)
+	mux.HandleFunc("POST "+apiPrefix+"/clips", h.allowExtension(h.auth(h.clip)))
+	return mux
+}
+
+// auth checks the bearer token and that the store is unlocked before
+// calling next.
+func (h *apiHandler) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		expected := h.token()
+		if !ok || expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="toJot"`)
+			writeAPIError(w, http.StatusUnauthorized, "missing or wrong bearer token")
+			return
+		}
+		if h.store.EncryptionStatus().Locked {
+			writeAPIError(w, http.StatusLocked, errStoreLocked.Error())
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (h *apiHandler) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+func (h *apiHandler) listJots(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, h.store.ListJots())
+}
+
+func (h *apiHandler) readJot(w http.ResponseWriter, r *http.Request) {
+	jot, ok := h.store.Get(r.PathValue("id"))
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("jot %s not found", r.PathValue("id")))
+		return
+	}
+	if !wantsMarkdown(r) {
+		writeAPIJSON(w, http.StatusOK, jot)
+		return
+	}
+	if jot.Lock != nil {
+		writeAPIError(w, http.StatusLocked, errJotLocked.Error())
+		return
+	}
+	markdown, err := h.store.Export([]string{jot.ID}, ExportOptions{Format: ExportMarkdown})
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	io.WriteString(w, markdown)
+}
+
+func (h *apiHandler) createJot(w http.ResponseWriter, r *http.Request) {
+	request, err := readAPIJotRequest(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	title := strings.TrimSpace(request.Title)
+	if title == "" {
+		title = untitledJotTitle
+	}
+	content := request.Content
+	if content == nil {
+		content = MarkdownToDocument([]byte(request.Markdown))
+	}
+	saved, err := h.store.Save(&Jot{ID: uuid.NewString(), Title: title, Content: content})
+	if err != nil {
+		writeAPIStoreError(w, err)
+		return
+	}
+	h.changed(saved)
+	w.Header().Set("Location", apiPrefix+"/jots/"+saved.ID)
+	writeAPIJSON(w, http.StatusCreated, saved)
+}
+
+func (h *apiHandler) appendJot(w http.ResponseWriter, r *http.Request) {
+	request, err := readAPIJotRequest(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var blocks []*Node
+	if request.Content != nil {
+		blocks = request.Content.Content
+	} else {
+		blocks = MarkdownToDocument([]byte(request.Markdown)).Content
+	}
+	if len(blocks) == 0 {
+		writeAPIError(w, http.StatusBadRequest, "nothing to append")
+		return
+	}
+	id := r.PathValue("id")
+	if _, ok := h.store.Get(id); !ok {
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("jot %s not found", id))
+		return
+	}
+	saved, err := h.store.Append(id, blocks)
+	if err != nil {
+		writeAPIStoreError(w, err)
+		return
+	}
+	h.changed(saved)
+	writeAPIJSON(w, http.StatusOK, saved)
+}
+
+func (h *apiHandler) search(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeAPIError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+	limit := 20
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeAPIError(w, http.StatusBadRequest, "limit must be a number of 0 or more")
+			return
+		}
+		limit = n
+	}
+	writeAPIJSON(w, http.StatusOK, h.store.Search(query, limit))
+}
+
+func (h *apiHandler) clip(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxAPIBodySize+1))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("error reading body: %v", err))
+		return
+	}
+	if len(data) > maxAPIBodySize {
+		writeAPIError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("body is larger than %d MB", maxAPIBodySize>>20))
+		return
+	}
+	var request ClipRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+	saved, err := h.store.Clip(request)
+	if errors.Is(err, errStoreLocked) {
+		writeAPIStoreError(w, err)
+		return
+	} else if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.changed(saved)
+	if h.onClip != nil {
+		h.onClip(saved)
+	}
+	w.Header().Set("Location", apiPrefix+"/jots/"+saved.ID)
+	writeAPIJSON(w, http.StatusCreated, saved)
+}
+
+// clipPreflight answers the CORS preflight of a browser extension posting
+// a clip.
+func (h *apiHandler) clipPreflight(w http.ResponseWriter, r *http.Request) {
+	if !extensionOrigin(r.Header.Get("Origin")) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	header := w.Header()
+	header.Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+	header.Set("Access-Control-Allow-Methods", "POST")
+	header.Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+	header.Set("Access-Control-Max-Age", "600")
+	header.Add("Vary", "Origin")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowExtension lets browser extensions read the response of next. Web
+// pages are refused, so a site cannot post clips even with a stolen token.
+func (h *apiHandler) allowExtension(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" {
+			if !extensionOrigin(origin) {
+				writeAPIError(w, http.StatusForbidden, fmt.Sprintf("origin %s is not allowed", origin))
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		next(w, r)
+	}
+}
+
+// extensionOrigin reports whether an origin is a browser extension.
+func extensionOrigin(origin string) bool {
+	for _, scheme := range []string{"chrome-extension://", "moz-extension://", "safari-web-extension://"} {
+		if strings.HasPrefix(origin, scheme) && len(origin) > len(scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// changed reports a jot the API created or changed.
+func (h *apiHandler) changed(jot *Jot) {
+	if h.onChange != nil {
+		h.onChange(jot)
+	}
+}
+
+// readAPIJotRequest reads the body of a request creating or appending to a
+// jot: JSON, or Markdown with the title in the title query parameter.
+func readAPIJotRequest(r *http.Request) (*apiJotRequest, error) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxAPIBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading body: %w", err)
+	}
+	if len(data) > maxAPIBodySize {
+		return nil, fmt.Errorf("body is larger than %d MB", maxAPIBodySize>>20)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/markdown", "text/plain":
+		return &apiJotRequest{Title: r.URL.Query().Get("title"), Markdown: string(data)}, nil
+	case "application/json", "":
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", mediaType)
+	}
+
+	var request apiJotRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if request.Content != nil && request.Markdown != "" {
+		return nil, fmt.Errorf("give either markdown or content, not both")
+	}
+	if request.Content != nil && request.Content.Type != "doc" {
+		return nil, fmt.Errorf("content is not a document")
+	}
+	return &request, nil
+}
+
+// wantsMarkdown reports whether a request asks for Markdown, with the format
+// query parameter or the Accept header, instead of JSON.
+func wantsMarkdown(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "markdown", "md":
+		return true
+	case "json":
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/markdown")
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, apiError{Error: message})
+}
+
+// writeAPIStoreError reports an error from the store.
+func writeAPIStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errStoreLocked) || errors.Is(err, errJotLocked) {
+		writeAPIError(w, http.StatusLocked, err.Error())
+		return
+	}
+	writeAPIError(w, http.StatusInternalServerError, err.Error())
+}
+
+// APIServer runs the local API on 127.0.0.1 while it is enabled in the
+// settings.
+type APIServer struct {
+	mu      sync.Mutex
+	handler http.Handler
+	server  *http.Server
+	addr    string
+	err     error
+}
+
+// NewAPIServer creates a stopped server for handler.
+func NewAPIServer(handler http.Handler) *APIServer {
+	return &APIServer{handler: handler}
+}
+
+// Apply starts, stops or moves the server to match the settings.
+func (s *APIServer) Apply(settings Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(settings.APIPort))
+	if s.server != nil && (!settings.APIEnabled || s.addr != addr) {
+		s.stopLocked()
+	}
+	s.err = nil
+	if !settings.APIEnabled || s.server != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		s.err = fmt.Errorf("error starting API server: %w", err)
+		return s.err
+	}
+	server := &http.Server{
+		Handler:           s.handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.server, s.addr = server, addr
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("Error serving API: %v\n", err)
+		}
+	}()
+	fmt.Printf("API listening on http://%s%s\n", addr, apiPrefix)
+	return nil
+}
+
+// Stop shuts the server down.
+func (s *APIServer) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopLocked()
+}
+
+func (s *APIServer) stopLocked() {
+	if s.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		fmt.Printf("Error stopping API server: %v\n", err)
+	}
+	s.server, s.addr = nil, ""
+}
+
+// Status returns whether the server runs, where, and why it failed to start.
+func (s *APIServer) Status() (running bool, addr string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.server != nil, s.addr, s.err
+}
+
+// apiClient calls the local API of the running toJot window, for processes
+// that must not write the store while the window owns it.
+type apiClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// newLocalAPIClient returns a client for the local API configured in
+// dataDir, or an error if the API is turned off.
+func newLocalAPIClient(dataDir string) (*apiClient, error) {
+	settings := NewSettingsStore(dataDir)
+	if err := settings.Load(); err != nil {
+		return nil, err
+	}
+	current := settings.Get()
+	if !current.APIEnabled {
+		return nil, fmt.Errorf("the toJot window is open and its local API is off; turn on the API or close the window")
+	}
+	token, err := LoadAPIToken(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	return &apiClient{
+		baseURL: "http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(current.APIPort)) + apiPrefix,
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// post sends a jot request to an endpoint and returns the jot it changed.
+func (c *apiClient) post(path string, request apiJotRequest) (*Jot, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error calling toJot API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr apiError
+		if json.NewDecoder(resp.Body).Decode(&apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		if resp.StatusCode == http.StatusLocked {
+			return nil, fmt.Errorf("%w: %s", errStoreLocked, apiErr.Error)
+		}
+		return nil, fmt.Errorf("toJot API: %s", apiErr.Error)
+	}
+	var jot Jot
+	if err := json.NewDecoder(resp.Body).Decode(&jot); err != nil {
+		return nil, fmt.Errorf("error reading toJot API response: %w", err)
+	}
+	return &jot, nil
+}
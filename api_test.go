@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testAPIToken = "test-token"
+
+// newTestAPI returns an API handler on an empty store, and the jots it
+// reported as changed.
+func newTestAPI(t *testing.T) (http.Handler, *Store, *[]*Jot) {
+	t.Helper()
+	store := NewStore(t.TempDir())
+	if err := store.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var changed []*Jot
+	handler := NewAPIHandler(store, func() string { return testAPIToken }, func(jot *Jot) {
+		changed = append(changed, jot)
+	}, nil)
+	return handler, store, &changed
+}
+
+// serveAPI sends a request with the test token to handler.
+func serveAPI(handler http.Handler, method, target, contentType, body string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+testAPIToken)
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	for key, value := range header {
+		r.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// decodeAPIJot reads the jot in a response.
+func decodeAPIJot(t *testing.T, w *httptest.ResponseRecorder) *Jot {
+	t.Helper()
+	var jot Jot
+	if err := json.Unmarshal(w.Body.Bytes(), &jot); err != nil {
+		t.Fatalf("decoding jot from %q: %v", w.Body.String(), err)
+	}
+	return &jot
+}
+
+func TestAPIAuth(t *testing.T) {
+	handler, _, _ := newTestAPI(t)
+	for name, authorization := range map[string]string{
+		"missing": "",
+		"wrong":   "Bearer wrong-token",
+		"scheme":  "Basic " + testAPIToken,
+	} {
+		r := httptest.NewRequest(http.MethodGet, apiPrefix+"/jots", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s token: status %d, want %d", name, w.Code, http.StatusUnauthorized)
+		}
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s token: no WWW-Authenticate header", name)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, apiPrefix+"/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("OpenAPI document: status %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestAPILockedStore(t *testing.T) {
+	handler, store, _ := newTestAPI(t)
+	if err := store.EnableEncryption("correct horse"); err != nil {
+		t.Fatalf("EnableEncryption: %v", err)
+	}
+	store.Lock()
+
+	for _, target := range []string{apiPrefix + "/jots", apiPrefix + "/search?q=x"} {
+		if w := serveAPI(handler, http.MethodGet, target, "", "", nil); w.Code != http.StatusLocked {
+			t.Errorf("GET %s: status %d, want %d", target, w.Code, http.StatusLocked)
+		}
+	}
+	w := serveAPI(handler, http.MethodPost, apiPrefix+"/jots", "application/json", `{"markdown":"x"}`, nil)
+	if w.Code != http.StatusLocked {
+		t.Errorf("POST jots: status %d, want %d", w.Code, http.StatusLocked)
+	}
+}
+
+func TestAPICreateAndRead(t *testing.T) {
+	handler, _, changed := newTestAPI(t)
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		title       string
+		text        string
+	}{
+		{
+			name:        "json markdown",
+			target:      apiPrefix + "/jots",
+			contentType: "application/json",
+			body:        `{"title":"From JSON","markdown":"# Heading\n\nSome **text**"}`,
+			title:       "From JSON",
+			text:        "Heading\nSome text",
+		},
+		{
+			name:        "markdown body",
+			target:      apiPrefix + "/jots?title=From+Markdown",
+			contentType: "text/markdown; charset=utf-8",
+			body:        "- one\n- two\n",
+			title:       "From Markdown",
+			text:        "one\ntwo",
+		},
+		{
+			name:        "tiptap content",
+			target:      apiPrefix + "/jots",
+			contentType: "application/json",
+			body:        `{"title":"From TipTap","content":{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Hello"}]}]}}`,
+			title:       "From TipTap",
+			text:        "Hello",
+		},
+		{
+			name:        "untitled",
+			target:      apiPrefix + "/jots",
+			contentType: "application/json",
+			body:        `{"markdown":"body"}`,
+			title:       untitledJotTitle,
+			text:        "body",
+		},
+	}
+	for _, test := range tests {
+		w := serveAPI(handler, http.MethodPost, test.target, test.contentType, test.body, nil)
+		if w.Code != http.StatusCreated {
+			t.Errorf("%s: status %d, want %d: %s", test.name, w.Code, http.StatusCreated, w.Body.String())
+			continue
+		}
+		jot := decodeAPIJot(t, w)
+		if jot.Title != test.title || jot.TextContent != test.text {
+			t.Errorf("%s: got title %q and text %q, want %q and %q", test.name, jot.Title, jot.TextContent, test.title, test.text)
+		}
+		if location := w.Header().Get("Location"); location != apiPrefix+"/jots/"+jot.ID {
+			t.Errorf("%s: Location %q", test.name, location)
+		}
+	}
+	if len(*changed) != len(tests) {
+		t.Errorf("%d jots reported as changed, want %d", len(*changed), len(tests))
+	}
+
+	for _, body := range []string{
+		`{"markdown":"x","content":{"type":"doc"}}`,
+		`{"content":{"type":"paragraph"}}`,
+		`not json`,
+	} {
+		if w := serveAPI(handler, http.MethodPost, apiPrefix+"/jots", "application/json", body, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("POST %s: status %d, want %d", body, w.Code, http.StatusBadRequest)
+		}
+	}
+	if w := serveAPI(handler, http.MethodPost, apiPrefix+"/jots", "image/png", "x", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("POST image: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := serveAPI(handler, http.MethodGet, apiPrefix+"/jots", "", "", nil)
+	var summaries []JotSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &summaries); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GET jots: status %d, %v", w.Code, err)
+	}
+	if len(summaries) != len(tests) {
+		t.Errorf("listed %d jots, want %d", len(summaries), len(tests))
+	}
+
+	id := (*changed)[0].ID
+	w = serveAPI(handler, http.MethodGet, apiPrefix+"/jots/"+id, "", "", nil)
+	if w.Code != http.StatusOK || decodeAPIJot(t, w).ID != id {
+		t.Errorf("GET jot as JSON: status %d: %s", w.Code, w.Body.String())
+	}
+	for name, request := range map[string]struct {
+		target string
+		header map[string]string
+	}{
+		"format": {target: apiPrefix + "/jots/" + id + "?format=md"},
+		"accept": {target: apiPrefix + "/jots/" + id, header: map[string]string{"Accept": "text/markdown"}},
+	} {
+		w := serveAPI(handler, http.MethodGet, request.target, "", "", request.header)
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/markdown") {
+			t.Errorf("GET jot as Markdown by %s: status %d, Content-Type %q", name, w.Code, w.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(w.Body.String(), "# Heading") || !strings.Contains(w.Body.String(), "Some **text**") {
+			t.Errorf("GET jot as Markdown by %s: %q", name, w.Body.String())
+		}
+	}
+	if w := serveAPI(handler, http.MethodGet, apiPrefix+"/jots/missing", "", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET missing jot: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestAPIAppend(t *testing.T) {
+	handler, store, _ := newTestAPI(t)
+	jot, err := store.Save(&Jot{ID: "a", Title: "Log", Content: MarkdownToDocument([]byte("first"))})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	w := serveAPI(handler, http.MethodPost, apiPrefix+"/jots/"+jot.ID+"/append", "text/markdown", "second", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("append: status %d: %s", w.Code, w.Body.String())
+	}
+	if text := decodeAPIJot(t, w).TextContent; text != "first\nsecond" {
+		t.Errorf("appended text %q, want %q", text, "first\nsecond")
+	}
+
+	for _, body := range []string{"", "   \n"} {
+		if w := serveAPI(handler, http.MethodPost, apiPrefix+"/jots/"+jot.ID+"/append", "text/markdown", body, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("append %q: status %d, want %d", body, w.Code, http.StatusBadRequest)
+		}
+	}
+	if w := serveAPI(handler, http.MethodPost, apiPrefix+"/jots/missing/append", "text/markdown", "x", nil); w.Code != http.StatusNotFound {
+		t.Errorf("append to missing jot: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestAPISearch(t *testing.T) {
+	handler, store, _ := newTestAPI(t)
+	if _, err := store.Save(&Jot{ID: "a", Title: "Groceries", Content: MarkdownToDocument([]byte("apples and pears"))}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	w := serveAPI(handler, http.MethodGet, apiPrefix+"/search?q=apples", "", "", nil)
+	var results []json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("search: status %d, %v", w.Code, err)
+	}
+	if len(results) != 1 {
+		t.Errorf("search found %d jots, want 1", len(results))
+	}
+
+	for _, target := range []string{
+		apiPrefix + "/search",
+		apiPrefix + "/search?q=%20",
+		apiPrefix + "/search?q=apples&limit=many",
+		apiPrefix + "/search?q=apples&limit=-1",
+	} {
+		if w := serveAPI(handler, http.MethodGet, target, "", "", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want %d", target, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestAPIClipOrigins(t *testing.T) {
+	handler, _, _ := newTestAPI(t)
+	const clip = `{"url":"https://example.com/page","title":"Page","html":"<p>Clipped</p>","selection":true}`
+
+	tests := []struct {
+		origin    string
+		preflight int
+		post      int
+	}{
+		{origin: "chrome-extension://abcdef", preflight: http.StatusNoContent, post: http.StatusCreated},
+		{origin: "moz-extension://0123-4567", preflight: http.StatusNoContent, post: http.StatusCreated},
+		{origin: "https://example.com", preflight: http.StatusForbidden, post: http.StatusForbidden},
+		{origin: "chrome-extension://", preflight: http.StatusForbidden, post: http.StatusForbidden},
+		{origin: "", preflight: http.StatusForbidden, post: http.StatusCreated},
+	}
+	for _, test := range tests {
+		header := map[string]string{}
+		if test.origin != "" {
+			header["Origin"] = test.origin
+		}
+
+		w := serveAPI(handler, http.MethodOptions, apiPrefix+"/clips", "", "", header)
+		if w.Code != test.preflight {
+			t.Errorf("preflight from %q: status %d, want %d", test.origin, w.Code, test.preflight)
+		}
+		if allowed := w.Header().Get("Access-Control-Allow-Origin"); (test.preflight == http.StatusNoContent) != (allowed == test.origin && allowed != "") {
+			t.Errorf("preflight from %q: Access-Control-Allow-Origin %q", test.origin, allowed)
+		}
+
+		w = serveAPI(handler, http.MethodPost, apiPrefix+"/clips", "application/json", clip, header)
+		if w.Code != test.post {
+			t.Errorf("clip from %q: status %d, want %d: %s", test.origin, w.Code, test.post, w.Body.String())
+		}
+		if allowed := w.Header().Get("Access-Control-Allow-Origin"); test.origin != "" && test.post == http.StatusCreated && allowed != test.origin {
+			t.Errorf("clip from %q: Access-Control-Allow-Origin %q", test.origin, allowed)
+		}
+	}
+
+	if w := serveAPI(handler, http.MethodPost, apiPrefix+"/clips", "application/json", `{"url":"javascript:alert(1)","html":"<p>x</p>"}`, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("clip of a javascript URL: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAPIClient(t *testing.T) {
+	handler, store, _ := newTestAPI(t)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := &apiClient{baseURL: server.URL + apiPrefix, token: testAPIToken, client: server.Client()}
+	jot, err := client.post("/jots", apiJotRequest{Title: "Remote", Markdown: "over the wire"})
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	if stored, ok := store.Get(jot.ID); !ok || stored.TextContent != "over the wire" {
+		t.Errorf("stored jot %+v", stored)
+	}
+	if _, err := client.post("/jots/missing/append", apiJotRequest{Markdown: "x"}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("append to missing jot: %v", err)
+	}
+
+	client.token = "wrong-token"
+	if _, err := client.post("/jots", apiJotRequest{Markdown: "x"}); err == nil {
+		t.Errorf("post with a wrong token succeeded")
+	}
+}
@@ -2,30 +2,127 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/options"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct
 type App struct {
-	ctx     context.Context
-	updater *UpdaterService
+	ctx      context.Context
+	updater  *UpdaterService
+	store    *Store
+	settings *SettingsStore
+	// lastActivity is the Unix time in nanoseconds of the last user
+	// activity, for auto-lock.
+	lastActivity atomic.Int64
+	dataDir      string
+	// instanceLock is the lock on the data directory, and instanceErr the
+	// reason it could not be taken.
+	instanceLock *instanceLock
+	instanceErr  error
+	// launchMu guards the links that arrived before startup, the launch
+	// request waiting for the store to be unlocked, and the jot to show once
+	// the frontend has started.
+	launchMu      sync.Mutex
+	started       bool
+	launchURLs    [][]string
+	pendingLaunch *launchRequest
+	launchJot     string
+	frontendReady bool
+	apiServer     *APIServer
+	apiToken      atomic.Value
+	capture       captureState
+	// closing is set once the frontend has been asked to clear the jots it
+	// keeps before the window closes, and closeReady once it has.
+	closing    atomic.Bool
+	closeReady atomic.Bool
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
 	updater := NewUpdaterService("daan-gunnink", "toJot")
-	return &App{
-		updater: updater,
+
+	dataDir, err := DefaultDataDir()
+	if err != nil {
+		fmt.Printf("Error finding data directory: %v\n", err)
+		dataDir = "."
+	}
+
+	app := &App{
+		updater:  updater,
+		store:    NewStore(dataDir),
+		settings: NewSettingsStore(dataDir),
+		dataDir:  dataDir,
 	}
+	app.apiToken.Store("")
+	app.apiServer = NewAPIServer(NewAPIHandler(app.store, func() string {
+		return app.apiToken.Load().(string)
+	}, app.emitJotChanged, app.emitJotClipped))
+	return app
+}
+
+// lockInstance locks the data directory for this window. If another window
+// holds it, Wails passes the arguments of this launch to that window and
+// exits.
+func (a *App) lockInstance() error {
+	a.instanceLock, a.instanceErr = lockDataDir(a.dataDir, instanceLockWait)
+	return a.instanceErr
 }
 
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	if errors.Is(a.instanceErr, errInstanceRunning) {
+		// Another window owns the data; domReady tells the user and quits.
+		return
+	}
 	a.updater.Initialize(ctx)
-	
+
+	if err := a.settings.Load(); err != nil {
+		fmt.Printf("Error loading settings: %v\n", err)
+	}
+	if err := a.store.Load(); err != nil {
+		fmt.Printf("Error loading store: %v\n", err)
+	}
+	workingDir, _ := os.Getwd()
+	a.launchMu.Lock()
+	a.started = true
+	launches := append([][]string{os.Args[1:]}, a.launchURLs...)
+	a.launchURLs = nil
+	a.launchMu.Unlock()
+	for _, args := range launches {
+		if err := a.launchArgs(args, workingDir); err != nil {
+			fmt.Printf("Error handling launch arguments: %v\n", err)
+		}
+	}
+
+	if token, err := LoadAPIToken(a.dataDir); err != nil {
+		fmt.Printf("Error loading API token: %v\n", err)
+	} else {
+		a.apiToken.Store(token)
+	}
+	if err := a.apiServer.Apply(a.settings.Get()); err != nil {
+		fmt.Printf("%v\n", err)
+	}
+
+	go runTrashPurge(ctx, a.store, a.settings)
+	go runAutoBackup(ctx, a.store, a.settings)
+	a.ReportActivity()
+	go runAutoLock(ctx, a.store, a.settings, func() time.Time {
+		return time.Unix(0, a.lastActivity.Load())
+	}, func() {
+		wailsRuntime.EventsEmit(ctx, "store:locked")
+	})
+
 	// Check for updates on startup (after a short delay to let the UI load)
 	go func() {
 		time.Sleep(2 * time.Second)
@@ -33,18 +130,178 @@ func (a *App) startup(ctx context.Context) {
 	}()
 }
 
+// shutdown is called when the app is closing
+func (a *App) shutdown(ctx context.Context) {
+	a.apiServer.Stop()
+}
+
+// closeTimeout is how long the window waits for the frontend to clear its
+// jots before it closes anyway.
+const closeTimeout = 2 * time.Second
+
+// beforeClose keeps the window of an unlocked encrypted store open until
+// the frontend has cleared the decrypted jots it keeps in the browser
+// database, so they are not left on disk after toJot quits
+func (a *App) beforeClose(ctx context.Context) bool {
+	if a.closeReady.Load() || errors.Is(a.instanceErr, errInstanceRunning) {
+		return false
+	}
+	if status := a.store.EncryptionStatus(); !status.Encrypted || status.Locked {
+		return false
+	}
+	if a.closing.Swap(true) {
+		return true
+	}
+	wailsRuntime.EventsEmit(ctx, "app:quitting")
+	go func() {
+		time.Sleep(closeTimeout)
+		a.ConfirmQuit()
+	}()
+	return true
+}
+
+// ConfirmQuit closes toJot once the frontend has cleared its jots
+func (a *App) ConfirmQuit() {
+	if a.closeReady.Swap(true) {
+		return
+	}
+	wailsRuntime.Quit(a.ctx)
+}
+
+// domReady is called when the frontend has loaded
+func (a *App) domReady(ctx context.Context) {
+	if !errors.Is(a.instanceErr, errInstanceRunning) {
+		return
+	}
+	wailsRuntime.MessageDialog(ctx, wailsRuntime.MessageDialogOptions{
+		Type:    wailsRuntime.ErrorDialog,
+		Title:   "toJot is already running",
+		Message: fmt.Sprintf("Another toJot window is using %s. Close it before opening toJot again.", a.dataDir),
+	})
+	wailsRuntime.Quit(ctx)
+}
+
+// onSecondInstanceLaunch brings the window to the front when toJot is
+// launched again, and acts on the arguments of that launch
+func (a *App) onSecondInstanceLaunch(data options.SecondInstanceData) {
+	wailsRuntime.WindowUnminimise(a.ctx)
+	wailsRuntime.WindowShow(a.ctx)
+	if err := a.launchArgs(data.Args, data.WorkingDirectory); err != nil {
+		wailsRuntime.MessageDialog(a.ctx, wailsRuntime.MessageDialogOptions{
+			Type:    wailsRuntime.ErrorDialog,
+			Title:   "toJot",
+			Message: err.Error(),
+		})
+	}
+}
+
+// onURLOpen handles a tojot:// link macOS opens the app with. Links that
+// arrive before startup are handled once the store is loaded.
+func (a *App) onURLOpen(url string) {
+	a.launchMu.Lock()
+	if !a.started {
+		a.launchURLs = append(a.launchURLs, []string{url})
+		a.launchMu.Unlock()
+		return
+	}
+	a.launchMu.Unlock()
+	a.onSecondInstanceLaunch(options.SecondInstanceData{Args: []string{url}})
+}
+
+// launchArgs acts on the arguments of a launch of toJot
+func (a *App) launchArgs(args []string, workingDir string) error {
+	request, err := parseLaunchArgs(args, workingDir)
+	if err != nil {
+		return err
+	}
+	return a.launch(request)
+}
+
+// launch carries out a launch request and shows the jot it created or
+// changed. While the store is locked the request waits until it is unlocked.
+func (a *App) launch(request launchRequest) error {
+	if request.Command == "capture" {
+		a.beginCapture()
+		return nil
+	}
+	a.leaveCaptureOnly()
+	if request.Command == "" {
+		return nil
+	}
+	jot, err := a.store.runLaunch(request)
+	if errors.Is(err, errStoreLocked) {
+		a.launchMu.Lock()
+		a.pendingLaunch = &request
+		a.launchMu.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	a.emitJotChanged(jot)
+	a.openJot(jot.ID)
+	return nil
+}
+
+// openJot shows a jot in the window, or keeps it for TakeLaunchJot if the
+// frontend has not started yet.
+func (a *App) openJot(id string) {
+	a.launchMu.Lock()
+	defer a.launchMu.Unlock()
+
+	if !a.frontendReady {
+		a.launchJot = id
+		return
+	}
+	wailsRuntime.EventsEmit(a.ctx, "jot:open", id)
+}
+
+// TakeLaunchJot returns the id of the jot the launch of toJot asked to show,
+// or "" if there is none. The frontend calls it once it listens for jot:open.
+func (a *App) TakeLaunchJot() string {
+	a.launchMu.Lock()
+	defer a.launchMu.Unlock()
+
+	a.frontendReady = true
+	id := a.launchJot
+	a.launchJot = ""
+	return id
+}
+
+// CopyJotLink puts the tojot:// link to a jot on the clipboard and returns
+// it
+func (a *App) CopyJotLink(id string) (string, error) {
+	if _, ok := a.store.Get(id); !ok {
+		return "", fmt.Errorf("jot %s not found", id)
+	}
+	link := JotDeepLink(id)
+	if err := wailsRuntime.ClipboardSetText(a.ctx, link); err != nil {
+		return "", fmt.Errorf("error copying link: %w", err)
+	}
+	return link, nil
+}
+
+// GetJot returns a jot by id
+func (a *App) GetJot(id string) (*Jot, error) {
+	jot, ok := a.store.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("jot %s not found", id)
+	}
+	return jot, nil
+}
+
 // CheckForUpdates checks if updates are available and prompts the user if they are
 func (a *App) CheckForUpdates() string {
 	hasUpdate, latestVersion, err := a.updater.CheckForUpdates()
 	if err != nil {
 		return fmt.Sprintf("Error checking for updates: %s", err.Error())
 	}
-	
+
 	if hasUpdate {
-		return fmt.Sprintf("Update available! Version %s is available (current: %s)", 
+		return fmt.Sprintf("Update available! Version %s is available (current: %s)",
 			latestVersion, Version)
 	}
-	
+
 	return fmt.Sprintf("You're running the latest version: %s", Version)
 }
 
@@ -55,12 +312,602 @@ func (a *App) DownloadAndInstallUpdate() string {
 	if err != nil {
 		return fmt.Sprintf("Error downloading update: %s", err.Error())
 	}
-	
+
 	// Apply the update
 	err = a.updater.ApplyUpdate(downloadPath, updateInfo)
 	if err != nil {
 		return fmt.Sprintf("Error applying update: %s", err.Error())
 	}
-	
+
 	return "Update process initiated. Please follow any instructions that appear."
 }
+
+// SaveJot stores a jot, replacing any existing jot with the same id
+func (a *App) SaveJot(jot Jot) (*Jot, error) {
+	a.ReportActivity()
+	result, err := a.store.SaveJot(&jot)
+	if err != nil {
+		return nil, err
+	}
+	for _, relabelled := range result.Relabelled {
+		a.emitJotChanged(relabelled)
+	}
+	if len(result.Repairs) > 0 {
+		a.emitJotChanged(result.Jot)
+		a.emitJotRepaired(result.Jot, result.Repairs)
+	}
+	return result.Jot, nil
+}
+
+// DeleteJot moves a jot to the trash and returns the links in other jots
+// that were left pointing at it
+func (a *App) DeleteJot(id string) ([]BrokenLink, error) {
+	return a.store.Delete(id)
+}
+
+// GetBacklinks returns the jots that contain a link to the given jot
+func (a *App) GetBacklinks(id string) []*Jot {
+	return a.store.Backlinks(id)
+}
+
+// GetLinkGraph returns all jots and the links between them for a graph view
+func (a *App) GetLinkGraph() LinkGraph {
+	return a.store.LinkGraph()
+}
+
+// FindBrokenLinks returns every link that points at a jot which no longer exists
+func (a *App) FindBrokenLinks() []BrokenLink {
+	return a.store.BrokenLinks()
+}
+
+// UnlinkBrokenLinks turns broken links into plain text. An empty id converts
+// broken links to any jot, otherwise only links to that jot are converted
+func (a *App) UnlinkBrokenLinks(id string) (int, error) {
+	changed, converted, err := a.store.UnlinkBrokenLinks(id)
+	if err != nil {
+		return 0, err
+	}
+	for _, jot := range changed {
+		a.emitJotChanged(jot)
+	}
+	return converted, nil
+}
+
+// ListRevisions returns the saved revisions of a jot, newest first
+func (a *App) ListRevisions(jotID string) ([]RevisionInfo, error) {
+	return a.store.Revisions(jotID)
+}
+
+// GetRevision returns a single revision of a jot including its content
+func (a *App) GetRevision(jotID, revisionID string) (*Revision, error) {
+	return a.store.Revision(jotID, revisionID)
+}
+
+// DiffRevisions compares the plain text of two revisions of a jot. An empty
+// toID compares against the current version of the jot
+func (a *App) DiffRevisions(jotID, fromID, toID string) ([]DiffLine, error) {
+	return a.store.DiffRevisions(jotID, fromID, toID)
+}
+
+// RestoreRevision makes a revision the current version of a jot
+func (a *App) RestoreRevision(jotID, revisionID string) (*Jot, error) {
+	result, err := a.store.RestoreRevision(jotID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	a.emitJotChanged(result.Jot)
+	for _, relabelled := range result.Relabelled {
+		a.emitJotChanged(relabelled)
+	}
+	return result.Jot, nil
+}
+
+// emitJotChanged tells the frontend that a jot was changed on the Go side
+func (a *App) emitJotChanged(jot *Jot) {
+	if a.ctx != nil {
+		wailsRuntime.EventsEmit(a.ctx, "jot:changed", jot)
+	}
+}
+
+// JotRepair is a jot whose content was changed on save to follow the
+// editor schema, and what was changed.
+type JotRepair struct {
+	ID       string          `json:"id"`
+	Problems []SchemaProblem `json:"problems"`
+}
+
+// emitJotRepaired tells the frontend that the content of a saved jot was
+// repaired
+func (a *App) emitJotRepaired(jot *Jot, problems []SchemaProblem) {
+	if a.ctx != nil {
+		wailsRuntime.EventsEmit(a.ctx, "jot:repaired", JotRepair{ID: jot.ID, Problems: problems})
+	}
+}
+
+// emitJotClipped tells the frontend that a web page was clipped to a jot
+func (a *App) emitJotClipped(jot *Jot) {
+	if a.ctx != nil {
+		wailsRuntime.EventsEmit(a.ctx, "jot:clipped", jot)
+	}
+}
+
+// ListTrash returns the jots in the trash, most recently deleted first
+func (a *App) ListTrash() []*Jot {
+	return a.store.Trash()
+}
+
+// RestoreFromTrash moves a jot out of the trash
+func (a *App) RestoreFromTrash(id string) (*Jot, error) {
+	jot, err := a.store.RestoreFromTrash(id)
+	if err != nil {
+		return nil, err
+	}
+	a.emitJotChanged(jot)
+	return jot, nil
+}
+
+// EmptyTrash permanently deletes all jots in the trash
+func (a *App) EmptyTrash() (int, error) {
+	return a.store.EmptyTrash()
+}
+
+// GetSettings returns the current settings
+func (a *App) GetSettings() Settings {
+	return a.settings.Get()
+}
+
+// UpdateSettings saves new settings and starts or stops the local API to
+// match them
+func (a *App) UpdateSettings(settings Settings) error {
+	if err := a.settings.Update(settings); err != nil {
+		return err
+	}
+	return a.apiServer.Apply(settings)
+}
+
+// GetAPIStatus returns whether the local API is enabled and running, its
+// address and its bearer token
+func (a *App) GetAPIStatus() APIStatus {
+	running, addr, err := a.apiServer.Status()
+	status := APIStatus{
+		Enabled: a.settings.Get().APIEnabled,
+		Running: running,
+		Token:   a.apiToken.Load().(string),
+	}
+	if running {
+		status.Address = "http://" + addr + apiPrefix
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// RegenerateAPIToken replaces the bearer token of the local API, locking out
+// clients that use the old one
+func (a *App) RegenerateAPIToken() (string, error) {
+	token, err := NewAPIToken(a.dataDir)
+	if err != nil {
+		return "", err
+	}
+	a.apiToken.Store(token)
+	return token, nil
+}
+
+// ListTags returns all tags with the number of jots using each of them
+func (a *App) ListTags() []TagInfo {
+	return a.store.Tags()
+}
+
+// ListJotsByTag returns the jots tagged with a tag or one of its nested tags
+func (a *App) ListJotsByTag(tag string) ([]*Jot, error) {
+	return a.store.JotsByTag(tag)
+}
+
+// RenameTag renames a tag in every jot, merging it into the new tag if that
+// already exists
+func (a *App) RenameTag(from, to string) (int, error) {
+	changed, err := a.store.RenameTag(from, to)
+	if err != nil {
+		return 0, err
+	}
+	for _, jot := range changed {
+		a.emitJotChanged(jot)
+	}
+	return len(changed), nil
+}
+
+// GetFolderTree returns all folders as a tree, with the jots in each folder
+func (a *App) GetFolderTree() *FolderTreeNode {
+	return a.store.FolderTree()
+}
+
+// CreateFolder creates a folder inside parentID, or at the top level if
+// parentID is empty
+func (a *App) CreateFolder(name, parentID string) (*Folder, error) {
+	return a.store.CreateFolder(name, parentID)
+}
+
+// RenameFolder changes the name of a folder
+func (a *App) RenameFolder(id, name string) (*Folder, error) {
+	return a.store.RenameFolder(id, name)
+}
+
+// MoveFolder moves a folder into another folder, or to the top level if
+// parentID is empty
+func (a *App) MoveFolder(id, parentID string) (*Folder, error) {
+	return a.store.MoveFolder(id, parentID)
+}
+
+// DeleteFolder removes a folder, moving its contents to its parent
+func (a *App) DeleteFolder(id string) error {
+	return a.store.DeleteFolder(id)
+}
+
+// SetFolderSortOrder sets how jots in a folder are sorted: "updated",
+// "created", "title" or "manual"
+func (a *App) SetFolderSortOrder(id, order string) (*Folder, error) {
+	return a.store.SetFolderSortOrder(id, order)
+}
+
+// ReorderFolders stores the order of the subfolders of a folder after a drag
+// and drop
+func (a *App) ReorderFolders(parentID string, ids []string) error {
+	return a.store.ReorderFolders(parentID, ids)
+}
+
+// MoveJots moves jots into a folder, or to the top level if folderID is empty
+func (a *App) MoveJots(ids []string, folderID string) (int, error) {
+	return a.store.MoveJots(ids, folderID)
+}
+
+// ReorderJots stores the order of the jots in a folder after a drag and drop
+func (a *App) ReorderJots(folderID string, ids []string) error {
+	return a.store.ReorderJots(folderID, ids)
+}
+
+// ListJots returns all jots with pinned jots first, then the rest by most
+// recently updated
+func (a *App) ListJots() []JotSummary {
+	return a.store.ListJots()
+}
+
+// ListFavourites returns the jots marked as favourite
+func (a *App) ListFavourites() []JotSummary {
+	return a.store.Favourites()
+}
+
+// PinJot pins a jot to the top of the list
+func (a *App) PinJot(id string) (*Jot, error) {
+	return a.store.SetPinned(id, true)
+}
+
+// UnpinJot removes a jot from the pinned jots
+func (a *App) UnpinJot(id string) (*Jot, error) {
+	return a.store.SetPinned(id, false)
+}
+
+// SetFavourite marks or unmarks a jot as favourite
+func (a *App) SetFavourite(id string, favourite bool) (*Jot, error) {
+	return a.store.SetFavourite(id, favourite)
+}
+
+// ReorderPinned stores the order of the pinned jots after a drag and drop
+func (a *App) ReorderPinned(ids []string) error {
+	return a.store.ReorderPinned(ids)
+}
+
+// CreateBackup writes a backup archive to path, asking the user for a file
+// if path is empty. It returns nil if the dialog was cancelled.
+func (a *App) CreateBackup(path string) (*BackupManifest, error) {
+	if path == "" {
+		var err error
+		path, err = wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+			Title:           "Save backup",
+			DefaultFilename: autoBackupPrefix + time.Now().Format(autoBackupTimeLayout) + ".zip",
+			Filters:         []wailsRuntime.FileFilter{{DisplayName: "Backups (*.zip)", Pattern: "*.zip"}},
+		})
+		if err != nil || path == "" {
+			return nil, err
+		}
+	}
+	return CreateBackup(a.store, path)
+}
+
+// RestoreBackup restores a backup archive from path, asking the user for a
+// file if path is empty. With merge set the backup is merged into the
+// existing jots by id, otherwise it replaces them. password opens an
+// encrypted backup made with another password than the store's.
+func (a *App) RestoreBackup(path string, merge bool, password string) (*RestoreReport, error) {
+	if path == "" {
+		var err error
+		path, err = wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+			Title:   "Restore backup",
+			Filters: []wailsRuntime.FileFilter{{DisplayName: "Backups (*.zip)", Pattern: "*.zip"}},
+		})
+		if err != nil || path == "" {
+			return nil, err
+		}
+	}
+
+	report, removed, err := RestoreBackup(a.store, path, merge, password)
+	if err != nil {
+		return nil, err
+	}
+	for _, jot := range a.store.List() {
+		a.emitJotChanged(jot)
+	}
+	for _, id := range removed {
+		a.emitJotDeleted(id)
+	}
+	return report, nil
+}
+
+// ChooseBackupDirectory asks the user for the automatic backup directory and
+// saves it in the settings
+func (a *App) ChooseBackupDirectory() (Settings, error) {
+	settings := a.settings.Get()
+	dir, err := wailsRuntime.OpenDirectoryDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title:                "Choose backup folder",
+		DefaultDirectory:     settings.BackupDirectory,
+		CanCreateDirectories: true,
+	})
+	if err != nil || dir == "" {
+		return settings, err
+	}
+	settings.BackupDirectory = dir
+	if err := a.settings.Update(settings); err != nil {
+		return a.settings.Get(), err
+	}
+	return settings, nil
+}
+
+// emitJotDeleted tells the frontend that a jot was removed on the Go side
+func (a *App) emitJotDeleted(id string) {
+	if a.ctx != nil {
+		wailsRuntime.EventsEmit(a.ctx, "jot:deleted", id)
+	}
+}
+
+// GetEncryptionStatus returns whether the store is encrypted and locked. The
+// frontend shows the unlock screen while it is locked.
+func (a *App) GetEncryptionStatus() EncryptionStatus {
+	return a.store.EncryptionStatus()
+}
+
+// GetAllJots returns every jot, for filling the frontend database after the
+// store is unlocked
+func (a *App) GetAllJots() []*Jot {
+	return a.store.List()
+}
+
+// EnableEncryption encrypts the store with a password
+func (a *App) EnableEncryption(password string) error {
+	return a.store.EnableEncryption(password)
+}
+
+// DisableEncryption decrypts the store
+func (a *App) DisableEncryption(password string) error {
+	return a.store.DisableEncryption(password)
+}
+
+// ChangePassword re-encrypts the store with a new password
+func (a *App) ChangePassword(oldPassword, newPassword string) error {
+	return a.store.ChangePassword(oldPassword, newPassword)
+}
+
+// Unlock opens the encrypted store
+func (a *App) Unlock(password string) error {
+	if err := a.store.Unlock(password); err != nil {
+		return err
+	}
+	a.ReportActivity()
+	wailsRuntime.EventsEmit(a.ctx, "store:unlocked")
+
+	a.launchMu.Lock()
+	request := a.pendingLaunch
+	a.pendingLaunch = nil
+	a.launchMu.Unlock()
+	if request != nil {
+		if err := a.launch(*request); err != nil {
+			fmt.Printf("Error handling launch arguments: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// Lock locks the encrypted store right away
+func (a *App) Lock() {
+	if !a.store.EncryptionStatus().Encrypted {
+		return
+	}
+	a.store.Lock()
+	wailsRuntime.EventsEmit(a.ctx, "store:locked")
+}
+
+// ReportActivity resets the auto-lock timer
+func (a *App) ReportActivity() {
+	a.lastActivity.Store(time.Now().UnixNano())
+}
+
+// LockJot encrypts a jot with its own password
+func (a *App) LockJot(id, password string) (*Jot, error) {
+	jot, err := a.store.LockJot(id, password)
+	if err != nil {
+		return nil, err
+	}
+	a.emitJotChanged(jot)
+	return jot, nil
+}
+
+// UnlockJot decrypts a locked jot for this session and returns its content.
+// The content is not sent to the frontend database.
+func (a *App) UnlockJot(id, password string) (*Jot, error) {
+	return a.store.UnlockJot(id, password)
+}
+
+// RelockJot locks a jot that was unlocked for this session
+func (a *App) RelockJot(id string) {
+	a.store.RelockJot(id)
+}
+
+// RemoveJotLock permanently decrypts a locked jot
+func (a *App) RemoveJotLock(id, password string) (*Jot, error) {
+	jot, err := a.store.RemoveJotLock(id, password)
+	if err != nil {
+		return nil, err
+	}
+	a.emitJotChanged(jot)
+	return jot, nil
+}
+
+// ExportJots renders jots as Markdown, HTML or plain text and returns the
+// result, for copying to the clipboard
+func (a *App) ExportJots(ids []string, options ExportOptions) (string, error) {
+	return a.store.Export(ids, options)
+}
+
+// SaveExport renders jots and saves them to a file chosen in a save dialog.
+// It returns the path of the file, or "" if the dialog was cancelled.
+func (a *App) SaveExport(ids []string, options ExportOptions) (string, error) {
+	content, err := a.store.Export(ids, options)
+	if err != nil {
+		return "", err
+	}
+	path, err := wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		Title:           "Export",
+		DefaultFilename: a.store.ExportFileName(ids, options),
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	if err := writeFileAtomic(path, []byte(content)); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// SavePDF renders jots to a PDF saved to a file chosen in a save dialog. It
+// returns the path of the file, or "" if the dialog was cancelled.
+func (a *App) SavePDF(ids []string, options PDFOptions) (string, error) {
+	data, err := a.store.ExportPDF(ids, options)
+	if err != nil {
+		return "", err
+	}
+	path, err := wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		Title:           "Export PDF",
+		DefaultFilename: strings.TrimSuffix(a.store.ExportFileName(ids, ExportOptions{}), ".md") + ".pdf",
+		Filters:         []wailsRuntime.FileFilter{{DisplayName: "PDF (*.pdf)", Pattern: "*.pdf"}},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ImportMarkdownFolder imports a folder of Markdown files, such as an
+// Obsidian vault, asking the user for the folder if dir is empty. It returns
+// nil if the dialog was cancelled.
+func (a *App) ImportMarkdownFolder(dir string) (*ImportReport, error) {
+	if dir == "" {
+		var err error
+		dir, err = wailsRuntime.OpenDirectoryDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+			Title: "Import Markdown folder",
+		})
+		if err != nil || dir == "" {
+			return nil, err
+		}
+	}
+
+	report, err := ImportMarkdownFolder(a.store, dir)
+	if err != nil {
+		return nil, err
+	}
+	a.emitImported(report)
+	return report, nil
+}
+
+// emitImported tells the frontend about the jots added by an import.
+func (a *App) emitImported(report *ImportReport) {
+	for _, id := range report.IDs {
+		if jot, ok := a.store.Get(id); ok {
+			a.emitJotChanged(jot)
+		}
+	}
+}
+
+// ImportENEX imports an Evernote export, asking the user for the file if
+// path is empty. With dryRun set nothing is stored and the report says what
+// would be imported. It returns nil if the dialog was cancelled.
+func (a *App) ImportENEX(path string, dryRun bool) (*ImportReport, error) {
+	if path == "" {
+		var err error
+		path, err = wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+			Title:   "Import from Evernote",
+			Filters: []wailsRuntime.FileFilter{{DisplayName: "Evernote exports (*.enex)", Pattern: "*.enex"}},
+		})
+		if err != nil || path == "" {
+			return nil, err
+		}
+	}
+
+	report, err := ImportENEX(a.store, path, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	a.emitImported(report)
+	return report, nil
+}
+
+// ImportKeep imports a Google Keep Takeout zip or folder, asking the user
+// for the zip if path is empty. With dryRun set nothing is stored and the
+// report says what would be imported. It returns nil if the dialog was
+// cancelled.
+func (a *App) ImportKeep(path string, dryRun bool) (*ImportReport, error) {
+	if path == "" {
+		var err error
+		path, err = wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+			Title:   "Import from Google Keep",
+			Filters: []wailsRuntime.FileFilter{{DisplayName: "Takeout archives (*.zip)", Pattern: "*.zip"}},
+		})
+		if err != nil || path == "" {
+			return nil, err
+		}
+	}
+
+	report, err := ImportKeep(a.store, path, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	a.emitImported(report)
+	return report, nil
+}
+
+// ImportFromBrowserStore adds a chunk of the jots in the browser database to
+// the Go store. Chunks can be sent again after an interruption without
+// creating duplicates.
+func (a *App) ImportFromBrowserStore(payload BrowserStoreChunk) (*ImportReport, error) {
+	return a.store.ImportBrowserChunk(payload)
+}
+
+// GetBrowserStoreMigration returns the progress of the move of jots from the
+// browser database, or nil if it has not started
+func (a *App) GetBrowserStoreMigration() *Migration {
+	return a.store.Migration(browserStoreMigration)
+}
+
+// ReindexAll recomputes the search text of every jot and rebuilds the link
+// and tag indexes. It returns the number of jots whose text changed.
+func (a *App) ReindexAll() (int, error) {
+	changed, err := a.store.ReindexAll()
+	if err != nil {
+		return 0, err
+	}
+	for _, jot := range changed {
+		a.emitJotChanged(jot)
+	}
+	return len(changed), nil
+}
@@ -0,0 +1,653 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupFormatVersion is the version of the backup archive layout.
+const backupFormatVersion = 1
+
+// Limits on what is read from a backup archive. Entries are read up to the
+// size in the manifest, or up to maxBackupEntrySize for archives without
+// sizes.
+const (
+	maxBackupManifestSize = 4 << 20
+	maxBackupEntrySize    = 512 << 20
+)
+
+// Names of the files inside a backup archive.
+const (
+	backupManifestName = "manifest.json"
+	backupStoreName    = "store.json"
+	backupMarkdownDir  = "markdown"
+)
+
+// Automatic backups are named with this prefix and a timestamp, so sorting
+// the names sorts them by age.
+const (
+	autoBackupPrefix     = "toJot-backup-"
+	autoBackupTimeLayout = "20060102-150405"
+	autoBackupInterval   = 15 * time.Minute
+)
+
+// BackupManifest describes the contents of a backup archive.
+type BackupManifest struct {
+	FormatVersion int       `json:"formatVersion"`
+	AppVersion    string    `json:"appVersion"`
+	SchemaVersion int       `json:"schemaVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	JotCount      int       `json:"jotCount"`
+	// Encrypted is set when the store in the archive is sealed like an
+	// encrypted store file. Such archives hold no Markdown copies.
+	Encrypted bool `json:"encrypted,omitempty"`
+	// Checksums holds the SHA-256 of every other file in the archive, and
+	// Sizes their size in bytes.
+	Checksums map[string]string `json:"checksums"`
+	Sizes     map[string]int64  `json:"sizes,omitempty"`
+	// Attachments lists the attachment files in the archive. Jots cannot
+	// hold attachments yet, so it is always empty.
+	Attachments []string `json:"attachments"`
+}
+
+// RestoreReport summarizes what a restore changed.
+type RestoreReport struct {
+	Restored int `json:"restored"`
+	Skipped  int `json:"skipped"`
+	Removed  int `json:"removed"`
+	// Repaired is the number of restored jots whose content was changed to
+	// follow the editor schema.
+	Repaired int `json:"repaired"`
+}
+
+// Snapshot returns a copy of everything in the store, in the on-disk layout.
+func (s *Store) Snapshot() storeFile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	file := storeFile{Version: storeSchemaVersion}
+	for _, jot := range sortJots(s.jots) {
+		file.Jots = append(file.Jots, jot.clone())
+	}
+	for _, jot := range sortJots(s.trash) {
+		file.Trash = append(file.Trash, jot.clone())
+	}
+	for _, folder := range s.folders {
+		clone := *folder
+		file.Folders = append(file.Folders, &clone)
+	}
+	sort.Slice(file.Folders, func(i, j int) bool {
+		return file.Folders[i].ID < file.Folders[j].ID
+	})
+	return file
+}
+
+// BackupSnapshot returns a copy of everything in the store for a backup.
+// The contents of an encrypted store are sealed with its data key, under
+// the same password header as the store file, so a backup never holds
+// plain text the store does not.
+func (s *Store) BackupSnapshot() (storeFile, error) {
+	file := s.Snapshot()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.encryption == nil {
+		return file, nil
+	}
+	if s.key == nil {
+		return storeFile{}, errStoreLocked
+	}
+	header := *s.encryption
+	header.PreviousKey = nil
+	file.Encryption = &header
+	if err := sealStoreFile(&file, s.key); err != nil {
+		return storeFile{}, err
+	}
+	return file, nil
+}
+
+// openBackup opens the contents of a sealed backup, with the data key of
+// the store if the backup was made with it, or else with the password the
+// backup was made with.
+func (s *Store) openBackup(file *storeFile, password string) error {
+	if file.Sealed == nil {
+		return nil
+	}
+	s.mu.RLock()
+	key := s.key
+	s.mu.RUnlock()
+	if key != nil {
+		opened := *file
+		if openStoreFile(&opened, key) == nil {
+			opened.Encryption = nil
+			*file = opened
+			return nil
+		}
+	}
+
+	if file.Encryption == nil {
+		return fmt.Errorf("backup is sealed but has no encryption header")
+	}
+	if password == "" {
+		return errBackupPassword
+	}
+	dataKey, _, err := file.Encryption.openDataKeys(password)
+	if err != nil {
+		return err
+	}
+	if key, err = newNoteKey(dataKey); err != nil {
+		return err
+	}
+	if err := openStoreFile(file, key); err != nil {
+		return err
+	}
+	file.Encryption = nil
+	return nil
+}
+
+// ReplaceAll replaces the whole contents of the store, and deletes the
+// revisions of the jots it removes. It returns the ids of the jots that were
+// removed and the number of jots whose content was repaired.
+func (s *Store) ReplaceAll(file storeFile) ([]string, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change := storeChange{
+		jots:    make(map[string]*Jot),
+		trash:   make(map[string]*Jot),
+		folders: make(map[string]*Folder),
+	}
+	for id := range s.jots {
+		change.jots[id] = nil
+	}
+	for id := range s.trash {
+		change.trash[id] = nil
+	}
+	for id := range s.folders {
+		change.folders[id] = nil
+	}
+	repaired := 0
+	for _, jot := range file.Jots {
+		change.jots[jot.ID] = restoredJot(jot, &repaired)
+	}
+	for _, jot := range file.Trash {
+		change.trash[jot.ID] = restoredJot(jot, &repaired)
+	}
+	for _, folder := range file.Folders {
+		clone := *folder
+		change.folders[folder.ID] = &clone
+	}
+
+	var removed []string
+	for id, jot := range change.jots {
+		if jot == nil {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+
+	if err := s.writeLocked(change); err != nil {
+		return nil, 0, err
+	}
+	// Jots that are in neither the jots nor the trash of the backup are gone,
+	// and so are their revisions.
+	for _, ids := range []map[string]*Jot{change.jots, change.trash} {
+		for id := range ids {
+			if change.jots[id] != nil || change.trash[id] != nil {
+				continue
+			}
+			if err := s.revisions.Remove(id); err != nil {
+				fmt.Printf("Error removing revisions of %s: %v\n", id, err)
+			}
+		}
+	}
+	return removed, repaired, nil
+}
+
+// Merge adds the jots and folders of file to the store. A jot that already
+// exists is only replaced if the merged copy was updated more recently.
+func (s *Store) Merge(file storeFile) (RestoreReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var report RestoreReport
+	change := storeChange{
+		jots:    make(map[string]*Jot),
+		trash:   make(map[string]*Jot),
+		folders: make(map[string]*Folder),
+	}
+	for _, folder := range file.Folders {
+		if _, ok := s.folders[folder.ID]; !ok {
+			clone := *folder
+			change.folders[folder.ID] = &clone
+		}
+	}
+	for _, jot := range file.Jots {
+		existing, ok := s.jots[jot.ID]
+		if ok && !jot.UpdatedAt.After(existing.UpdatedAt) {
+			report.Skipped++
+			continue
+		}
+		change.jots[jot.ID] = restoredJot(jot, &report.Repaired)
+		if _, inTrash := s.trash[jot.ID]; inTrash {
+			change.trash[jot.ID] = nil
+		}
+		report.Restored++
+	}
+	for _, jot := range file.Trash {
+		_, live := s.jots[jot.ID]
+		_, trashed := s.trash[jot.ID]
+		if _, merged := change.jots[jot.ID]; live || trashed || merged {
+			continue
+		}
+		change.trash[jot.ID] = restoredJot(jot, &report.Repaired)
+	}
+
+	if err := s.writeLocked(change); err != nil {
+		return RestoreReport{}, err
+	}
+	return report, nil
+}
+
+// restoredJot returns a copy of a jot from a backup with its content made
+// to follow the editor schema, as a save would, and counts it in repaired
+// if that changed it. The content of a jot locked with its own password is
+// sealed and kept as it is.
+func restoredJot(jot *Jot, repaired *int) *Jot {
+	restored := jot.clone()
+	if restored.Lock != nil {
+		return restored
+	}
+	if content, problems := RepairDocument(restored.Content); len(problems) > 0 {
+		restored.Content = content
+		*repaired++
+	}
+	return restored
+}
+
+// WriteBackup writes a backup archive of file to w.
+func WriteBackup(w io.Writer, file storeFile) (*BackupManifest, error) {
+	manifest := &BackupManifest{
+		FormatVersion: backupFormatVersion,
+		AppVersion:    GetAppVersion(),
+		SchemaVersion: file.Version,
+		CreatedAt:     time.Now(),
+		JotCount:      len(file.Jots),
+		Encrypted:     file.Sealed != nil,
+		Checksums:     make(map[string]string),
+		Sizes:         make(map[string]int64),
+		Attachments:   []string{},
+	}
+
+	archive := zip.NewWriter(w)
+	add := func(name string, data []byte) error {
+		entry, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: manifest.CreatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("error adding %s to backup: %w", name, err)
+		}
+		if _, err := entry.Write(data); err != nil {
+			return fmt.Errorf("error writing %s to backup: %w", name, err)
+		}
+		if name != backupManifestName {
+			manifest.Checksums[name] = checksum(data)
+			manifest.Sizes[name] = int64(len(data))
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding store: %w", err)
+	}
+	if err := add(backupStoreName, data); err != nil {
+		return nil, err
+	}
+
+	if manifest.Encrypted {
+		manifest.JotCount = len(file.Sealed.Jots)
+	}
+	paths := folderPaths(file.Folders)
+	names := make(map[string]struct{})
+	for _, jot := range file.Jots {
+		dir := path.Join(backupMarkdownDir, paths[jot.FolderID])
+		name := uniqueFileName(names, dir, safeFileName(jot.Title), ".md")
+		if err := add(name, []byte(RenderMarkdown(jot.Content, nil))); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err = json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding manifest: %w", err)
+	}
+	if err := add(backupManifestName, data); err != nil {
+		return nil, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("error finishing backup: %w", err)
+	}
+	return manifest, nil
+}
+
+// ReadBackup reads and validates a backup archive. Every checksum is
+// verified and the store is parsed completely before anything is returned,
+// so a damaged archive never reaches the store.
+func ReadBackup(archivePath string) (*BackupManifest, storeFile, error) {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, storeFile{}, fmt.Errorf("error opening backup: %w", err)
+	}
+	defer archive.Close()
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, entry := range archive.File {
+		files[entry.Name] = entry
+	}
+	read := func(name string, limit int64) ([]byte, error) {
+		entry, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("backup is missing %s", name)
+		}
+		reader, err := entry.Open()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s from backup: %w", name, err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s from backup: %w", name, err)
+		}
+		if int64(len(data)) > limit {
+			return nil, fmt.Errorf("%s in backup is larger than %d bytes", name, limit)
+		}
+		return data, nil
+	}
+
+	data, err := read(backupManifestName, maxBackupManifestSize)
+	if err != nil {
+		return nil, storeFile{}, err
+	}
+	var manifest BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, storeFile{}, fmt.Errorf("error parsing backup manifest: %w", err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > backupFormatVersion {
+		return nil, storeFile{}, fmt.Errorf("unsupported backup format version %d", manifest.FormatVersion)
+	}
+	if manifest.SchemaVersion > storeSchemaVersion {
+		return nil, storeFile{}, fmt.Errorf("backup schema version %d is newer than supported version %d", manifest.SchemaVersion, storeSchemaVersion)
+	}
+	if _, ok := manifest.Checksums[backupStoreName]; !ok {
+		return nil, storeFile{}, fmt.Errorf("backup manifest has no checksum for %s", backupStoreName)
+	}
+
+	var store []byte
+	for name, sum := range manifest.Checksums {
+		limit := int64(maxBackupEntrySize)
+		if size, ok := manifest.Sizes[name]; ok && size >= 0 && size < limit {
+			limit = size
+		}
+		data, err := read(name, limit)
+		if err != nil {
+			return nil, storeFile{}, err
+		}
+		if checksum(data) != sum {
+			return nil, storeFile{}, fmt.Errorf("checksum mismatch for %s", name)
+		}
+		if name == backupStoreName {
+			store = data
+		}
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(store, &file); err != nil {
+		return nil, storeFile{}, fmt.Errorf("error parsing backup store: %w", err)
+	}
+	// A sealed store is checked once it is opened.
+	if file.Sealed == nil {
+		if err := validateStoreFile(file); err != nil {
+			return nil, storeFile{}, err
+		}
+	}
+	return &manifest, file, nil
+}
+
+// validateStoreFile checks that every jot and folder has a unique id.
+func validateStoreFile(file storeFile) error {
+	seen := make(map[string]struct{})
+	for _, jot := range append(append([]*Jot{}, file.Jots...), file.Trash...) {
+		if jot == nil || jot.ID == "" {
+			return fmt.Errorf("backup contains a jot without an id")
+		}
+		if _, ok := seen[jot.ID]; ok {
+			return fmt.Errorf("backup contains jot %s more than once", jot.ID)
+		}
+		seen[jot.ID] = struct{}{}
+	}
+	folders := make(map[string]struct{})
+	for _, folder := range file.Folders {
+		if folder == nil || folder.ID == "" {
+			return fmt.Errorf("backup contains a folder without an id")
+		}
+		folders[folder.ID] = struct{}{}
+	}
+	for _, folder := range file.Folders {
+		if _, ok := folders[folder.ParentID]; folder.ParentID != "" && !ok {
+			return fmt.Errorf("folder %s has an unknown parent %s", folder.ID, folder.ParentID)
+		}
+	}
+	return nil
+}
+
+// CreateBackup writes a backup of the store to archivePath. The backup of
+// an encrypted store is sealed with its key.
+func CreateBackup(store *Store, archivePath string) (*BackupManifest, error) {
+	file, err := store.BackupSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	manifest, err := WriteBackup(&buffer, file)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(archivePath, buffer.Bytes()); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// RestoreBackup validates a backup archive and then either replaces the
+// contents of the store with it or merges it in by id. A sealed backup made
+// with another password than the store's is opened with password. It
+// returns a report and the ids of the jots removed by a replace.
+func RestoreBackup(store *Store, archivePath string, merge bool, password string) (*RestoreReport, []string, error) {
+	_, file, err := ReadBackup(archivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if file.Sealed != nil {
+		if err := store.openBackup(&file, password); err != nil {
+			return nil, nil, err
+		}
+		if err := validateStoreFile(file); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if merge {
+		report, err := store.Merge(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &report, nil, nil
+	}
+
+	removed, repaired, err := store.ReplaceAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &RestoreReport{Restored: len(file.Jots), Removed: len(removed), Repaired: repaired}, removed, nil
+}
+
+// runAutoBackup creates a backup in the configured directory whenever the
+// latest one is older than the configured interval, and removes the oldest
+// automatic backups beyond the number to keep. It runs until ctx is done.
+func runAutoBackup(ctx context.Context, store *Store, settings *SettingsStore) {
+	ticker := time.NewTicker(autoBackupInterval)
+	defer ticker.Stop()
+
+	for {
+		// A locked store cannot be sealed into a backup; the backup is made
+		// once it is unlocked.
+		current := settings.Get()
+		if current.BackupDirectory != "" && current.BackupIntervalHours > 0 && !store.EncryptionStatus().Locked {
+			if err := autoBackup(store, current); err != nil {
+				fmt.Printf("Error creating automatic backup: %v\n", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// autoBackup creates an automatic backup if one is due and rotates old ones.
+func autoBackup(store *Store, settings Settings) error {
+	backups, err := listAutoBackups(settings.BackupDirectory)
+	if err != nil {
+		return err
+	}
+
+	interval := time.Duration(settings.BackupIntervalHours) * time.Hour
+	now := time.Now()
+	if len(backups) > 0 {
+		name := backups[len(backups)-1]
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, autoBackupPrefix), ".zip")
+		if last, err := time.ParseInLocation(autoBackupTimeLayout, stamp, time.Local); err == nil && now.Sub(last) < interval {
+			return nil
+		}
+	}
+
+	name := autoBackupPrefix + now.Format(autoBackupTimeLayout) + ".zip"
+	if _, err := CreateBackup(store, filepath.Join(settings.BackupDirectory, name)); err != nil {
+		return err
+	}
+	backups = append(backups, name)
+
+	if settings.BackupKeep > 0 && len(backups) > settings.BackupKeep {
+		for _, old := range backups[:len(backups)-settings.BackupKeep] {
+			if err := os.Remove(filepath.Join(settings.BackupDirectory, old)); err != nil {
+				fmt.Printf("Error removing old backup %s: %v\n", old, err)
+			}
+		}
+	}
+	return nil
+}
+
+// listAutoBackups returns the names of the automatic backups in dir, oldest
+// first.
+func listAutoBackups(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading backup directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, autoBackupPrefix) && strings.HasSuffix(name, ".zip") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// checksum returns the hex SHA-256 of data.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// folderPaths returns the slash-separated path of every folder by id. The
+// root has the empty path.
+func folderPaths(folders []*Folder) map[string]string {
+	byID := make(map[string]*Folder, len(folders))
+	for _, folder := range folders {
+		byID[folder.ID] = folder
+	}
+
+	paths := map[string]string{"": ""}
+	var resolve func(id string, depth int) string
+	resolve = func(id string, depth int) string {
+		if p, ok := paths[id]; ok {
+			return p
+		}
+		folder, ok := byID[id]
+		if !ok || depth > len(folders) {
+			return ""
+		}
+		p := path.Join(resolve(folder.ParentID, depth+1), safeFileName(folder.Name))
+		paths[id] = p
+		return p
+	}
+	for _, folder := range folders {
+		resolve(folder.ID, 0)
+	}
+	return paths
+}
+
+// safeFileName turns a title into a name that is valid as a file name on
+// every platform.
+func safeFileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	name = strings.Trim(name, ". ")
+	if len([]rune(name)) > 100 {
+		name = string([]rune(name)[:100])
+	}
+	if name == "" {
+		name = "Untitled"
+	}
+	return name
+}
+
+// uniqueFileName returns dir/base+ext, with a number added to base if that
+// path was already used, and records the result in used.
+func uniqueFileName(used map[string]struct{}, dir, base, ext string) string {
+	name := path.Join(dir, base+ext)
+	for i := 2; ; i++ {
+		if _, ok := used[strings.ToLower(name)]; !ok {
+			used[strings.ToLower(name)] = struct{}{}
+			return name
+		}
+		name = path.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+}
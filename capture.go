@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// inboxTitle is the title of the jot quick captures are appended to, created
+// by the first capture.
+const inboxTitle = "Inbox"
+
+// Size of the quick-capture window.
+const (
+	captureWidth  = 480
+	captureHeight = 240
+)
+
+// captureState is the window as it was before it switched to quick capture,
+// so it can be put back afterwards.
+type captureState struct {
+	mu sync.Mutex
+	// only is set when toJot was launched for a capture, in a window of its
+	// own that quits when the capture is done.
+	only      bool
+	active    bool
+	pending   bool
+	width     int
+	height    int
+	x         int
+	y         int
+	minimised bool
+}
+
+// isCaptureLaunch reports whether the arguments of a launch ask for quick
+// capture, to open the window in capture mode.
+func isCaptureLaunch(args []string) bool {
+	request, err := parseLaunchArgs(args, "")
+	return err == nil && request.Command == "capture"
+}
+
+// CaptureToInbox appends Markdown to the end of the inbox jot and returns
+// it. If the inbox is gone, a new one is created.
+func (s *Store) CaptureToInbox(inboxID, text string) (*Jot, error) {
+	blocks := MarkdownToDocument([]byte(text)).Content
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("nothing to capture")
+	}
+	if s.EncryptionStatus().Locked {
+		return nil, errStoreLocked
+	}
+	if inbox, ok := s.Get(inboxID); ok && inbox.DeletedAt == nil {
+		return s.Append(inbox.ID, blocks)
+	}
+	doc := NewDocument()
+	doc.Content = blocks
+	return s.Save(&Jot{ID: uuid.NewString(), Title: inboxTitle, Content: doc})
+}
+
+// CaptureJot creates a jot from Markdown, titled with its first line.
+func (s *Store) CaptureJot(text string) (*Jot, error) {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil, fmt.Errorf("nothing to capture")
+	}
+	title, body, _ := strings.Cut(text, "\n")
+	title = strings.TrimSpace(strings.TrimLeft(title, "#"))
+	if utf8.RuneCountInString(title) > maxDeepLinkTitle {
+		title = string([]rune(title)[:maxDeepLinkTitle])
+	}
+	if title == "" {
+		title = untitledJotTitle
+	}
+	return s.Save(&Jot{
+		ID:      uuid.NewString(),
+		Title:   title,
+		Content: MarkdownToDocument([]byte(body)),
+	})
+}
+
+// beginCapture switches the window to quick capture. A window opened for
+// the capture already has its size; the main window is shrunk and kept on
+// top until the capture is done. Wails cannot take the frame off a window
+// that is already open, so the main window keeps it.
+func (a *App) beginCapture() {
+	a.capture.mu.Lock()
+	defer a.capture.mu.Unlock()
+
+	if !a.capture.only && !a.capture.active {
+		a.capture.width, a.capture.height = wailsRuntime.WindowGetSize(a.ctx)
+		a.capture.x, a.capture.y = wailsRuntime.WindowGetPosition(a.ctx)
+		a.capture.minimised = wailsRuntime.WindowIsMinimised(a.ctx)
+		wailsRuntime.WindowUnminimise(a.ctx)
+		wailsRuntime.WindowSetSize(a.ctx, captureWidth, captureHeight)
+		wailsRuntime.WindowSetAlwaysOnTop(a.ctx, true)
+		wailsRuntime.WindowCenter(a.ctx)
+	}
+	a.capture.active = true
+	wailsRuntime.WindowShow(a.ctx)
+
+	a.launchMu.Lock()
+	defer a.launchMu.Unlock()
+	if !a.frontendReady {
+		a.capture.pending = true
+		return
+	}
+	wailsRuntime.EventsEmit(a.ctx, "capture:open")
+}
+
+// endCapture closes quick capture: a window opened for the capture quits,
+// and the main window gets its size, place and state back.
+func (a *App) endCapture() {
+	a.capture.mu.Lock()
+	defer a.capture.mu.Unlock()
+
+	if !a.capture.active {
+		return
+	}
+	a.capture.active = false
+	if a.capture.only {
+		wailsRuntime.Quit(a.ctx)
+		return
+	}
+	wailsRuntime.WindowSetAlwaysOnTop(a.ctx, false)
+	wailsRuntime.WindowSetSize(a.ctx, a.capture.width, a.capture.height)
+	wailsRuntime.WindowSetPosition(a.ctx, a.capture.x, a.capture.y)
+	if a.capture.minimised {
+		wailsRuntime.WindowMinimise(a.ctx)
+	}
+	wailsRuntime.EventsEmit(a.ctx, "capture:closed")
+}
+
+// leaveCaptureOnly turns a window opened for a capture into the main window
+// when toJot is launched again without asking for a capture.
+func (a *App) leaveCaptureOnly() {
+	a.capture.mu.Lock()
+	defer a.capture.mu.Unlock()
+
+	if !a.capture.only {
+		return
+	}
+	a.capture.only = false
+	a.capture.active = false
+	wailsRuntime.WindowSetAlwaysOnTop(a.ctx, false)
+	wailsRuntime.WindowSetSize(a.ctx, mainWidth, mainHeight)
+	wailsRuntime.WindowCenter(a.ctx)
+	wailsRuntime.EventsEmit(a.ctx, "capture:closed")
+}
+
+// TakeLaunchCapture reports whether the launch of toJot asked for quick
+// capture. The frontend calls it once it listens for capture:open.
+func (a *App) TakeLaunchCapture() bool {
+	a.launchMu.Lock()
+	a.frontendReady = true
+	a.launchMu.Unlock()
+
+	a.capture.mu.Lock()
+	defer a.capture.mu.Unlock()
+	pending := a.capture.pending
+	a.capture.pending = false
+	return pending
+}
+
+// Capture saves quick-capture text, appended to the inbox jot or as a new
+// jot, and closes quick capture
+func (a *App) Capture(text string, newJot bool) (*Jot, error) {
+	var jot *Jot
+	var err error
+	if newJot {
+		jot, err = a.store.CaptureJot(text)
+	} else {
+		settings := a.settings.Get()
+		jot, err = a.store.CaptureToInbox(settings.InboxJotID, text)
+		if err == nil && jot.ID != settings.InboxJotID {
+			settings.InboxJotID = jot.ID
+			if err := a.settings.Update(settings); err != nil {
+				fmt.Printf("Error saving inbox: %v\n", err)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.emitJotChanged(jot)
+	a.endCapture()
+	return jot, nil
+}
+
+// CancelCapture closes quick capture without saving
+func (a *App) CancelCapture() {
+	a.endCapture()
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Exit codes of the command line subcommands.
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitNotFound = 3
+	exitLocked   = 4
+)
+
+// passwordEnv names the environment variable the command line reads the
+// password of an encrypted store from.
+const passwordEnv = "TOJOT_PASSWORD"
+
+// exportPDF is the extra format of the export subcommand.
+const exportPDF = "pdf"
+
+const cliUsage = `Usage: toJot <command> [flags] [arguments]
+
+Without a command toJot opens its window.
+
+Commands:
+  new [title]            create a jot from Markdown on stdin or --body and
+                         print its id
+  list                   list jots, pinned first
+  show <id>              print a jot (--format md, text, html or json)
+  search <query>         search the titles and text of jots (--limit n)
+  append <id>            add Markdown on stdin or --body to the end of a jot
+  export <id>...         export jots as one document (--format, --out file)
+  export --all --out dir export every jot to its own file in dir
+  capture                open a small window to jot something down, added to
+                         the inbox jot or saved as a new jot
+  mcp                    serve jots to AI tools over the Model Context
+                         Protocol on stdin and stdout (--read-only)
+  help                   show this help
+
+Every command takes --json for machine readable output. An encrypted store
+is unlocked with the password in $` + passwordEnv + `.
+
+While the toJot window is open, new and append are sent on to the window,
+which shows the jot instead of the command printing it.
+
+Exit codes: 0 success, 1 error, 2 usage error, 3 jot not found,
+4 store or jot locked.
+`
+
+// cliError is an error that ends a subcommand with a specific exit code.
+type cliError struct {
+	code int
+	err  error
+}
+
+func (e *cliError) Error() string { return e.err.Error() }
+
+func (e *cliError) Unwrap() error { return e.err }
+
+// forwardError asks for a command to be sent on to the running window,
+// which holds the lock on the data directory.
+type forwardError struct {
+	args []string
+}
+
+func (e *forwardError) Error() string { return errInstanceRunning.Error() }
+
+// usageError reports a wrong invocation of a subcommand.
+func usageError(format string, args ...interface{}) error {
+	return &cliError{code: exitUsage, err: fmt.Errorf(format, args...)}
+}
+
+// cli runs one subcommand against the store in the data directory.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	store  *Store
+	lock   *instanceLock
+	json   bool
+}
+
+// isCLICommand reports whether name is a subcommand. Anything else, such as
+// the flags macOS passes to an app or a link to open, starts the window.
+func isCLICommand(name string) bool {
+	switch name {
+	case "new", "list", "show", "search", "append", "export", "mcp", "help", "--help", "-h":
+		return true
+	}
+	return false
+}
+
+// runCLI runs the subcommand named by the first argument. It returns the exit
+// code, and false if the window should be started instead: when the
+// arguments do not name a subcommand, or with the arguments to send on to the
+// running window in forward.
+func runCLI(args []string, stdin io.Reader, stdout, stderr io.Writer) (code int, forward []string, ok bool) {
+	if len(args) == 0 || !isCLICommand(args[0]) {
+		return exitOK, nil, false
+	}
+
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	err := c.run(args[0], args[1:])
+	if c.lock != nil {
+		c.lock.Release()
+	}
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitOK, nil, true
+	}
+	var forwardErr *forwardError
+	if errors.As(err, &forwardErr) {
+		fmt.Fprintf(stderr, "toJot is running; sending %s to its window\n", args[0])
+		return exitOK, forwardErr.args, false
+	}
+	fmt.Fprintf(stderr, "toJot %s: %v\n", args[0], err)
+	var cliErr *cliError
+	switch {
+	case errors.As(err, &cliErr):
+		if cliErr.code == exitUsage {
+			fmt.Fprintf(stderr, "Run 'toJot help' for usage.\n")
+		}
+		return cliErr.code, nil, true
+	case errors.Is(err, errStoreLocked), errors.Is(err, errJotLocked):
+		return exitLocked, nil, true
+	}
+	return exitError, nil, true
+}
+
+// run dispatches a subcommand.
+func (c *cli) run(command string, args []string) error {
+	switch command {
+	case "new":
+		return c.newJot(args)
+	case "list":
+		return c.list(args)
+	case "show":
+		return c.show(args)
+	case "search":
+		return c.search(args)
+	case "append":
+		return c.appendJot(args)
+	case "export":
+		return c.export(args)
+	case "mcp":
+		return c.mcp(args)
+	}
+	fmt.Fprint(c.stdout, cliUsage)
+	return nil
+}
+
+// flags returns the flag set of a subcommand, with the --json flag every
+// subcommand has.
+func (c *cli) flags(command string) *flag.FlagSet {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.BoolVar(&c.json, "json", false, "print JSON")
+	return flags
+}
+
+// parse parses the flags of a subcommand, which may come before or after its
+// arguments, and returns the arguments.
+func (c *cli) parse(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional, err := parseInterspersed(flags, args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return nil, &cliError{code: exitUsage, err: err}
+	}
+	return positional, err
+}
+
+// parseInterspersed parses flags that may come before or after the
+// arguments, and returns the arguments. Everything after "--" is an
+// argument.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// open loads the store, unlocking it with the password in the environment
+// if it is encrypted. A command that writes passes the arguments to send on
+// to the window if it is running; the data directory is locked for the
+// command otherwise, so the window does not start while it writes.
+func (c *cli) open(forward []string) error {
+	dataDir, err := DefaultDataDir()
+	if err != nil {
+		return err
+	}
+	if forward != nil {
+		lock, err := lockDataDir(dataDir, instanceLockWait)
+		if errors.Is(err, errInstanceRunning) {
+			return &forwardError{args: forward}
+		}
+		if err != nil {
+			return err
+		}
+		c.lock = lock
+	}
+	c.store = NewStore(dataDir)
+	if err := c.store.Load(); err != nil {
+		return err
+	}
+	if !c.store.EncryptionStatus().Locked {
+		return nil
+	}
+	password := os.Getenv(passwordEnv)
+	if password == "" {
+		return &cliError{code: exitLocked, err: fmt.Errorf("the store is encrypted; set %s to unlock it", passwordEnv)}
+	}
+	if err := c.store.Unlock(password); err != nil {
+		return &cliError{code: exitLocked, err: err}
+	}
+	return nil
+}
+
+// get returns a jot, failing with exitNotFound if there is none.
+func (c *cli) get(id string) (*Jot, error) {
+	jot, ok := c.store.Get(id)
+	if !ok {
+		return nil, &cliError{code: exitNotFound, err: fmt.Errorf("jot %s not found", id)}
+	}
+	return jot, nil
+}
+
+// readBody returns the Markdown given with --body, or else reads it from
+// stdin. Nothing is read when stdin is a terminal, so a command does not wait
+// for input that is not coming.
+func (c *cli) readBody(body string) ([]byte, error) {
+	if body != "" {
+		return []byte(body), nil
+	}
+	if file, ok := c.stdin.(*os.File); ok {
+		if info, err := file.Stat(); err != nil || info.Mode()&os.ModeCharDevice != 0 {
+			return nil, nil
+		}
+	}
+	data, err := io.ReadAll(io.LimitReader(c.stdin, maxImportFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading stdin: %w", err)
+	}
+	if len(data) > maxImportFileSize {
+		return nil, fmt.Errorf("input is larger than %d MB", maxImportFileSize>>20)
+	}
+	return data, nil
+}
+
+// printJSON writes v as indented JSON.
+func (c *cli) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printJot prints a changed jot: its id, or the whole jot with --json.
+func (c *cli) printJot(jot *Jot) error {
+	if c.json {
+		return c.printJSON(jot)
+	}
+	_, err := fmt.Fprintln(c.stdout, jot.ID)
+	return err
+}
+
+// newJot creates a jot from Markdown.
+func (c *cli) newJot(args []string) error {
+	flags := c.flags("new")
+	bodyFlag := flags.String("body", "", "Markdown body instead of stdin")
+	args, err := c.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	title := strings.TrimSpace(strings.Join(args, " "))
+	if title == "" {
+		title = untitledJotTitle
+	}
+	body, err := c.readBody(*bodyFlag)
+	if err != nil {
+		return err
+	}
+	if err := c.open([]string{"new", "--body", string(body), "--", title}); err != nil {
+		return err
+	}
+
+	saved, err := c.store.Save(&Jot{
+		ID:      uuid.NewString(),
+		Title:   title,
+		Content: MarkdownToDocument(body),
+	})
+	if err != nil {
+		return err
+	}
+	return c.printJot(saved)
+}
+
+// list prints every jot, pinned jots first.
+func (c *cli) list(args []string) error {
+	args, err := c.parse(c.flags("list"), args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError("list takes no arguments")
+	}
+	if err := c.open(nil); err != nil {
+		return err
+	}
+
+	jots := c.store.ListJots()
+	if c.json {
+		return c.printJSON(jots)
+	}
+	table := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	for _, jot := range jots {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", jot.ID, jot.UpdatedAt.Local().Format(time.DateTime), jot.Title)
+	}
+	return table.Flush()
+}
+
+// show prints one jot in an export format.
+func (c *cli) show(args []string) error {
+	flags := c.flags("show")
+	format := flags.String("format", "md", "output format: md, text, html or json")
+	args, err := c.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageError("show takes one jot id")
+	}
+	if *format == "json" {
+		c.json = true
+	}
+	exportFormat, err := cliExportFormat(*format)
+	if !c.json && (err != nil || exportFormat == exportPDF) {
+		return usageError("unknown format %q", *format)
+	}
+	if err := c.open(nil); err != nil {
+		return err
+	}
+
+	jot, err := c.get(args[0])
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(jot)
+	}
+	if jot.Lock != nil {
+		return errJotLocked
+	}
+	content, err := c.store.Export([]string{jot.ID}, ExportOptions{Format: exportFormat})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(c.stdout, content)
+	return err
+}
+
+// search prints the jots matching a query.
+func (c *cli) search(args []string) error {
+	flags := c.flags("search")
+	limit := flags.Int("limit", 20, "maximum number of results, 0 for all")
+	args, err := c.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	query := strings.TrimSpace(strings.Join(args, " "))
+	if query == "" {
+		return usageError("search needs a query")
+	}
+	if err := c.open(nil); err != nil {
+		return err
+	}
+
+	results := c.store.Search(query, *limit)
+	if c.json {
+		return c.printJSON(results)
+	}
+	for _, result := range results {
+		fmt.Fprintf(c.stdout, "%s  %s\n", result.ID, result.Title)
+		if result.Snippet != "" {
+			fmt.Fprintf(c.stdout, "    %s\n", result.Snippet)
+		}
+	}
+	return nil
+}
+
+// appendJot adds Markdown to the end of a jot.
+func (c *cli) appendJot(args []string) error {
+	flags := c.flags("append")
+	bodyFlag := flags.String("body", "", "Markdown to append instead of stdin")
+	args, err := c.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageError("append takes one jot id")
+	}
+	body, err := c.readBody(*bodyFlag)
+	if err != nil {
+		return err
+	}
+	blocks := MarkdownToDocument(body).Content
+	if len(blocks) == 0 {
+		return usageError("nothing to append; pipe Markdown to stdin or use --body")
+	}
+	if err := c.open([]string{"append", "--body", string(body), "--", args[0]}); err != nil {
+		return err
+	}
+
+	if _, err := c.get(args[0]); err != nil {
+		return err
+	}
+	saved, err := c.store.Append(args[0], blocks)
+	if err != nil {
+		return err
+	}
+	return c.printJot(saved)
+}
+
+// exportSummary is the result of exporting every jot to its own file.
+type exportSummary struct {
+	Dir   string   `json:"dir"`
+	Files []string `json:"files"`
+}
+
+// export renders jots as one document, or every jot to its own file.
+func (c *cli) export(args []string) error {
+	flags := c.flags("export")
+	format := flags.String("format", "md", "output format: md, text, html or pdf")
+	out := flags.String("out", "", "file to write, or with --all the directory")
+	all := flags.Bool("all", false, "export every jot to its own file")
+	args, err := c.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	exportFormat, err := cliExportFormat(*format)
+	if err != nil {
+		return usageError("unknown format %q", *format)
+	}
+	switch {
+	case *all && len(args) > 0:
+		return usageError("export takes either jot ids or --all")
+	case *all && *out == "":
+		return usageError("export --all needs --out with a directory")
+	case !*all && len(args) == 0:
+		return usageError("export needs jot ids or --all")
+	case exportFormat == exportPDF && *out == "":
+		return usageError("export to PDF needs --out")
+	}
+	if err := c.open(nil); err != nil {
+		return err
+	}
+
+	if *all {
+		return c.exportAll(*out, exportFormat)
+	}
+	for _, id := range args {
+		jot, err := c.get(id)
+		if err != nil {
+			return err
+		}
+		if jot.Lock != nil {
+			return errJotLocked
+		}
+	}
+	data, err := c.render(args, exportFormat, nil)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = c.stdout.Write(data)
+		return err
+	}
+	if err := writeFileAtomic(*out, data); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(exportSummary{Dir: filepath.Dir(*out), Files: []string{*out}})
+	}
+	return nil
+}
+
+// exportAll writes every jot to its own file in dir, with note links between
+// them pointing at the files. Jots locked with their own password are left
+// out.
+func (c *cli) exportAll(dir, format string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating export directory: %w", err)
+	}
+	ext := ".pdf"
+	if format != exportPDF {
+		ext = exportExtensions[format]
+	}
+
+	// Every file is named first, so links point at the file of the jot they
+	// link to even when titles are shared.
+	var jots []*Jot
+	files := make(map[string]string)
+	used := make(map[string]struct{})
+	for _, jot := range c.store.List() {
+		if jot.Lock != nil {
+			fmt.Fprintf(c.stderr, "Skipping locked jot %s\n", jot.ID)
+			continue
+		}
+		jots = append(jots, jot)
+		files[jot.ID] = uniqueFileName(used, "", safeFileName(jot.Title), ext)
+	}
+
+	summary := exportSummary{Dir: dir, Files: []string{}}
+	for _, jot := range jots {
+		data, err := c.render([]string{jot.ID}, format, files)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, files[jot.ID])
+		if err := writeFileAtomic(path, data); err != nil {
+			return err
+		}
+		summary.Files = append(summary.Files, path)
+	}
+	if c.json {
+		return c.printJSON(summary)
+	}
+	fmt.Fprintf(c.stdout, "Exported %d jots to %s\n", len(summary.Files), dir)
+	return nil
+}
+
+// render exports jots in a format of the export subcommand. With files set,
+// note links point at the files the jots are exported to.
+func (c *cli) render(ids []string, format string, files map[string]string) ([]byte, error) {
+	if format == exportPDF {
+		return c.store.ExportPDF(ids, PDFOptions{})
+	}
+	options := ExportOptions{Format: format, Links: ExportLinksTitle}
+	if files != nil {
+		options.Links, options.files = ExportLinksRelative, files
+	}
+	content, err := c.store.Export(ids, options)
+	return []byte(content), err
+}
+
+// cliExportFormat maps the format names of the command line to export
+// formats.
+func cliExportFormat(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "md", "markdown":
+		return ExportMarkdown, nil
+	case "txt", "text":
+		return ExportText, nil
+	case "html":
+		return ExportHTML, nil
+	case "pdf":
+		return exportPDF, nil
+	}
+	return "", fmt.Errorf("unknown format %q", name)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ClipRequest is a web page or a selection sent by a browser clipper.
+type ClipRequest struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	HTML  string `json:"html"`
+	// Selection is set when HTML is a selection rather than the whole page.
+	// A whole page is reduced to its main content; a selection is kept as
+	// it is.
+	Selection bool `json:"selection"`
+}
+
+// JotSource records the web page a clipped jot came from.
+type JotSource struct {
+	URL       string    `json:"url"`
+	Title     string    `json:"title,omitempty"`
+	ClippedAt time.Time `json:"clippedAt"`
+}
+
+// clipAllowedAttrs are the attributes kept when sanitizing clipped HTML, the
+// only ones the conversion to TipTap reads.
+var clipAllowedAttrs = map[string]bool{
+	"href":    true,
+	"alt":     true,
+	"type":    true,
+	"checked": true,
+	"start":   true,
+}
+
+// clipChromeElements are the parts of a page around its content, dropped
+// when a whole page without an article or main element is clipped.
+var clipChromeElements = map[atom.Atom]bool{
+	atom.Nav:    true,
+	atom.Footer: true,
+	atom.Aside:  true,
+}
+
+// Clip stores a clipped web page or selection as a new jot. The HTML is
+// sanitized and converted to the blocks the editor supports, under a line
+// naming the source, and the page is kept as the source of the jot.
+func (s *Store) Clip(request ClipRequest) (*Jot, error) {
+	source, err := url.Parse(strings.TrimSpace(request.URL))
+	if err != nil || (source.Scheme != "http" && source.Scheme != "https") || source.Host == "" {
+		return nil, fmt.Errorf("source URL must be an http or https URL")
+	}
+	if strings.TrimSpace(request.HTML) == "" {
+		return nil, fmt.Errorf("nothing to clip")
+	}
+	if len(request.HTML) > maxImportFileSize {
+		return nil, fmt.Errorf("clip is larger than %d MB", maxImportFileSize>>20)
+	}
+	if !utf8.ValidString(request.HTML) || !utf8.ValidString(request.Title) {
+		return nil, fmt.Errorf("clip is not UTF-8 text")
+	}
+
+	page, err := html.Parse(strings.NewReader(request.HTML))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing clip: %w", err)
+	}
+	title := strings.Join(strings.Fields(request.Title), " ")
+	if title == "" {
+		if element := findHTMLElement(page, atom.Title); element != nil {
+			title = strings.Join(strings.Fields(htmlText(element)), " ")
+		}
+	}
+	if title == "" {
+		title = source.Host
+	}
+
+	root := findHTMLElement(page, atom.Body)
+	if root == nil {
+		root = page
+	}
+	if !request.Selection {
+		if content := findHTMLElement(root, atom.Article); content != nil {
+			root = content
+		} else if content := findHTMLElement(root, atom.Main); content != nil {
+			root = content
+		} else {
+			removeHTMLElements(root, clipChromeElements)
+		}
+	}
+	sanitizeClipHTML(root)
+
+	doc := NewDocument()
+	doc.Content = append([]*Node{{
+		Type: "paragraph",
+		Content: []*Node{
+			{Type: "text", Text: "Clipped from " + source.String(), Marks: []Mark{{Type: "italic"}}},
+		},
+	}}, HTMLToTipTap(root)...)
+
+	return s.Save(&Jot{
+		ID:      uuid.NewString(),
+		Title:   title,
+		Content: doc,
+		Source: &JotSource{
+			URL:       source.String(),
+			Title:     title,
+			ClippedAt: time.Now(),
+		},
+	})
+}
+
+// sanitizeClipHTML removes from clipped HTML the elements that are never
+// converted, content the page hides, every attribute but the few the
+// conversion reads, and links that are not http, https or mailto.
+func sanitizeClipHTML(node *html.Node) {
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.CommentNode || child.Type == html.ElementNode && (ignoredHTMLElements[child.DataAtom] || hiddenHTMLElement(child)) {
+			node.RemoveChild(child)
+			child = next
+			continue
+		}
+		if child.Type == html.ElementNode {
+			attrs := child.Attr[:0]
+			for _, attr := range child.Attr {
+				if attr.Namespace != "" || !clipAllowedAttrs[attr.Key] {
+					continue
+				}
+				if attr.Key == "href" && !safeClipLink(attr.Val) {
+					continue
+				}
+				attrs = append(attrs, attr)
+			}
+			child.Attr = attrs
+		}
+		sanitizeClipHTML(child)
+		child = next
+	}
+}
+
+// hiddenHTMLElement reports whether a page hides an element from readers.
+func hiddenHTMLElement(node *html.Node) bool {
+	if hasHTMLAttr(node, "hidden") || htmlAttr(node, "aria-hidden") == "true" {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(htmlAttr(node, "style")), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// safeClipLink reports whether a link target may be kept.
+func safeClipLink(href string) bool {
+	link, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(link.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// removeHTMLElements removes the elements of the given types below node.
+func removeHTMLElements(node *html.Node, elements map[atom.Atom]bool) {
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.ElementNode && elements[child.DataAtom] {
+			node.RemoveChild(child)
+		} else {
+			removeHTMLElements(child, elements)
+		}
+		child = next
+	}
+}
+
+// htmlText returns the text inside an element.
+func htmlText(node *html.Node) string {
+	var text strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			text.WriteString(node.Data)
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+	return text.String()
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// dailyDateLayout is the layout of the date key of daily notes.
+const dailyDateLayout = "2006-01-02"
+
+// defaultDailyTitlePattern titles daily notes when no pattern is set, as in
+// "Monday 5 January 2026".
+const defaultDailyTitlePattern = "dddd D MMMM YYYY"
+
+// dailyTitleTokens are the parts of a date a title pattern can hold, longest
+// first so "MMMM" is not read as two "MM".
+var dailyTitleTokens = []string{"YYYY", "YY", "MMMM", "MMM", "MM", "M", "dddd", "ddd", "DD", "D"}
+
+// DailyNote is a date that has a daily note, for the calendar.
+type DailyNote struct {
+	Date  string `json:"date"`
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// ParseDailyDate reads the date key of a daily note.
+func ParseDailyDate(date string) (time.Time, error) {
+	day, err := time.ParseInLocation(dailyDateLayout, date, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+	}
+	return day, nil
+}
+
+// FormatDailyTitle formats the title of the daily note of a day. The pattern
+// holds YYYY, YY, MMMM (January), MMM (Jan), MM, M, dddd (Monday), ddd
+// (Mon), DD and D; other text is kept as it is.
+func FormatDailyTitle(pattern string, day time.Time) string {
+	if strings.TrimSpace(pattern) == "" {
+		pattern = defaultDailyTitlePattern
+	}
+	var title strings.Builder
+	for len(pattern) > 0 {
+		token := ""
+		for _, candidate := range dailyTitleTokens {
+			if strings.HasPrefix(pattern, candidate) {
+				token = candidate
+				break
+			}
+		}
+		if token == "" {
+			title.WriteByte(pattern[0])
+			pattern = pattern[1:]
+			continue
+		}
+		switch token {
+		case "YYYY":
+			title.WriteString(strconv.Itoa(day.Year()))
+		case "YY":
+			title.WriteString(fmt.Sprintf("%02d", day.Year()%100))
+		case "MMMM":
+			title.WriteString(day.Month().String())
+		case "MMM":
+			title.WriteString(day.Month().String()[:3])
+		case "MM":
+			title.WriteString(fmt.Sprintf("%02d", int(day.Month())))
+		case "M":
+			title.WriteString(strconv.Itoa(int(day.Month())))
+		case "dddd":
+			title.WriteString(day.Weekday().String())
+		case "ddd":
+			title.WriteString(day.Weekday().String()[:3])
+		case "DD":
+			title.WriteString(fmt.Sprintf("%02d", day.Day()))
+		case "D":
+			title.WriteString(strconv.Itoa(day.Day()))
+		}
+		pattern = pattern[len(token):]
+	}
+	return title.String()
+}
+
+// DailyNote returns the daily note of a date, creating it from the template
+// if there is none yet. created reports whether it was created. In the
+// template, {{date}} is replaced by the date and {{title}} by the title.
+func (s *Store) DailyNote(date string, settings Settings) (jot *Jot, created bool, err error) {
+	day, err := ParseDailyDate(date)
+	if err != nil {
+		return nil, false, err
+	}
+	if s.EncryptionStatus().Locked {
+		return nil, false, errStoreLocked
+	}
+
+	s.dailyMu.Lock()
+	defer s.dailyMu.Unlock()
+
+	if jot, ok := s.findDailyNote(date); ok {
+		return jot, false, nil
+	}
+	title := FormatDailyTitle(settings.DailyTitlePattern, day)
+	body := strings.NewReplacer("{{date}}", date, "{{title}}", title).Replace(settings.DailyTemplate)
+	jot, err = s.Save(&Jot{
+		ID:        uuid.NewString(),
+		Title:     title,
+		Content:   MarkdownToDocument([]byte(body)),
+		DailyDate: date,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return jot, true, nil
+}
+
+// AdjacentDailyNote returns the nearest daily note before the date, or after
+// it if next is set. Days without a daily note are skipped.
+func (s *Store) AdjacentDailyNote(date string, next bool) (*Jot, error) {
+	if _, err := ParseDailyDate(date); err != nil {
+		return nil, err
+	}
+	notes := s.DailyNotes("", "")
+	// Date keys sort like the dates they hold.
+	i := sort.Search(len(notes), func(i int) bool { return notes[i].Date >= date })
+	if next {
+		for i < len(notes) && notes[i].Date == date {
+			i++
+		}
+	} else {
+		i--
+	}
+	if i < 0 || i >= len(notes) {
+		direction := "before"
+		if next {
+			direction = "after"
+		}
+		return nil, fmt.Errorf("no daily note %s %s", direction, date)
+	}
+	jot, ok := s.Get(notes[i].ID)
+	if !ok {
+		return nil, fmt.Errorf("jot %s not found", notes[i].ID)
+	}
+	return jot, nil
+}
+
+// DailyNotes returns the dates from from to to, both included, that have a
+// daily note, in order. An empty from or to leaves that end open.
+func (s *Store) DailyNotes(from, to string) []DailyNote {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var notes []DailyNote
+	for _, jot := range s.jots {
+		if jot.DailyDate == "" || from != "" && jot.DailyDate < from || to != "" && jot.DailyDate > to {
+			continue
+		}
+		notes = append(notes, DailyNote{Date: jot.DailyDate, ID: jot.ID, Title: jot.Title})
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if notes[i].Date != notes[j].Date {
+			return notes[i].Date < notes[j].Date
+		}
+		return notes[i].ID < notes[j].ID
+	})
+	return notes
+}
+
+// findDailyNote returns the daily note of a date. If there are several, such
+// as one restored from the trash, the oldest is used.
+func (s *Store) findDailyNote(date string) (*Jot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found *Jot
+	for _, jot := range s.jots {
+		if jot.DailyDate == date && (found == nil || jot.CreatedAt.Before(found.CreatedAt)) {
+			found = jot
+		}
+	}
+	if found == nil {
+		return nil, false
+	}
+	return found.clone(), true
+}
+
+// OpenDailyNote returns the daily note of a date as YYYY-MM-DD, or of today
+// if date is empty, creating it if needed
+func (a *App) OpenDailyNote(date string) (*Jot, error) {
+	if date == "" {
+		date = time.Now().Format(dailyDateLayout)
+	}
+	jot, created, err := a.store.DailyNote(date, a.settings.Get())
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		a.emitJotChanged(jot)
+	}
+	return jot, nil
+}
+
+// OpenTodayNote returns today's daily note, creating it if needed
+func (a *App) OpenTodayNote() (*Jot, error) {
+	return a.OpenDailyNote("")
+}
+
+// OpenPreviousDailyNote returns the nearest daily note before a date
+func (a *App) OpenPreviousDailyNote(date string) (*Jot, error) {
+	return a.store.AdjacentDailyNote(date, false)
+}
+
+// OpenNextDailyNote returns the nearest daily note after a date
+func (a *App) OpenNextDailyNote(date string) (*Jot, error) {
+	return a.store.AdjacentDailyNote(date, true)
+}
+
+// GetDailyNotes returns the dates from from to to, both included, that have
+// a daily note, for a calendar
+func (a *App) GetDailyNotes(from, to string) ([]DailyNote, error) {
+	for _, date := range []string{from, to} {
+		if date == "" {
+			continue
+		}
+		if _, err := ParseDailyDate(date); err != nil {
+			return nil, err
+		}
+	}
+	return a.store.DailyNotes(from, to), nil
+}
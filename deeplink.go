@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// deepLinkScheme is the URL scheme of links to toJot, such as
+// tojot://open/<id>, tojot://new?title=...&body=... and tojot://capture.
+const deepLinkScheme = "tojot"
+
+// Limits on deep links, which can come from any web page or chat message.
+const (
+	maxDeepLinkLength = 64 << 10
+	maxDeepLinkTitle  = 500
+)
+
+// DeepLink is a parsed tojot:// link.
+type DeepLink struct {
+	// Action is "open" to show a jot, "new" to create one or "capture" for
+	// quick capture.
+	Action string
+	ID     string
+	Title  string
+	// Body is the Markdown of a new jot.
+	Body string
+}
+
+// JotDeepLink returns the tojot:// link that opens a jot.
+func JotDeepLink(id string) string {
+	return deepLinkScheme + "://open/" + url.PathEscape(id)
+}
+
+// isDeepLink reports whether s looks like a tojot:// link.
+func isDeepLink(s string) bool {
+	return len(s) > len(deepLinkScheme) && strings.EqualFold(s[:len(deepLinkScheme)+1], deepLinkScheme+":")
+}
+
+// ParseDeepLink parses and checks a tojot:// link. The id of an open link
+// may only hold letters, digits, "-" and "_", like the ids toJot creates.
+func ParseDeepLink(raw string) (*DeepLink, error) {
+	if len(raw) > maxDeepLinkLength {
+		return nil, fmt.Errorf("link is longer than %d KB", maxDeepLinkLength>>10)
+	}
+	link, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid link: %w", err)
+	}
+	if !strings.EqualFold(link.Scheme, deepLinkScheme) {
+		return nil, fmt.Errorf("not a %s:// link", deepLinkScheme)
+	}
+	if link.Opaque != "" || link.User != nil || link.Port() != "" {
+		return nil, fmt.Errorf("invalid link: expected %s://open/<id>, %s://new or %s://capture", deepLinkScheme, deepLinkScheme, deepLinkScheme)
+	}
+	query, err := url.ParseQuery(link.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid link: %w", err)
+	}
+	path := strings.Trim(link.Path, "/")
+
+	switch strings.ToLower(link.Hostname()) {
+	case "open":
+		if path == "" || strings.Contains(path, "/") {
+			return nil, fmt.Errorf("invalid link: expected %s://open/<id>", deepLinkScheme)
+		}
+		if !validJotID(path) {
+			return nil, fmt.Errorf("invalid jot id %q", path)
+		}
+		return &DeepLink{Action: "open", ID: path}, nil
+	case "new":
+		if path != "" {
+			return nil, fmt.Errorf("invalid link: expected %s://new?title=...&body=...", deepLinkScheme)
+		}
+		title, body := strings.TrimSpace(query.Get("title")), query.Get("body")
+		if !utf8.ValidString(title) || !utf8.ValidString(body) {
+			return nil, fmt.Errorf("invalid link: title and body must be UTF-8 text")
+		}
+		if utf8.RuneCountInString(title) > maxDeepLinkTitle {
+			return nil, fmt.Errorf("title is longer than %d characters", maxDeepLinkTitle)
+		}
+		return &DeepLink{Action: "new", Title: title, Body: body}, nil
+	case "capture":
+		if path != "" {
+			return nil, fmt.Errorf("invalid link: expected %s://capture", deepLinkScheme)
+		}
+		return &DeepLink{Action: "capture"}, nil
+	}
+	return nil, fmt.Errorf("unknown link action %q", link.Hostname())
+}
+
+// validJotID reports whether id only holds the characters of jot ids.
+func validJotID(id string) bool {
+	if len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return id != ""
+}
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// sealedBoxVersion is the version of the encrypted envelope. A new version
+// is needed whenever the cipher or the layout of a box changes.
+const sealedBoxVersion = 1
+
+// minPasswordLength is the shortest password accepted for encryption.
+const minPasswordLength = 8
+
+// autoLockCheckInterval is how often the auto-lock checks for inactivity.
+const autoLockCheckInterval = 30 * time.Second
+
+var (
+	errStoreLocked    = errors.New("store is locked")
+	errWrongPassword  = errors.New("wrong password")
+	errBackupPassword = errors.New("backup was made with another password; enter that password to restore it")
+)
+
+// SealedBox is an encrypted envelope. Data is sealed with XChaCha20-Poly1305
+// under the key identified by KeyID.
+type SealedBox struct {
+	Version int    `json:"v"`
+	KeyID   string `json:"kid,omitempty"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// noteKey is a key that seals and opens boxes.
+type noteKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// newNoteKey creates a key from 32 bytes of key material. The id is derived
+// from the key so boxes can name the key they were sealed with.
+func newNoteKey(key []byte) (*noteKey, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+	sum := sha256.Sum256(key)
+	return &noteKey{id: hex.EncodeToString(sum[:8]), aead: aead}, nil
+}
+
+// randomBytes returns n random bytes.
+func randomBytes(n int) ([]byte, error) {
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		return nil, fmt.Errorf("error generating random bytes: %w", err)
+	}
+	return data, nil
+}
+
+// seal encrypts plaintext. The additional data is authenticated but not
+// stored, so the same value must be passed to open.
+func (k *noteKey) seal(plaintext, additional []byte) (*SealedBox, error) {
+	nonce, err := randomBytes(k.aead.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+	return &SealedBox{
+		Version: sealedBoxVersion,
+		KeyID:   k.id,
+		Nonce:   nonce,
+		Data:    k.aead.Seal(nil, nonce, plaintext, additional),
+	}, nil
+}
+
+// open decrypts a box sealed with this key.
+func (k *noteKey) open(box *SealedBox, additional []byte) ([]byte, error) {
+	if box == nil {
+		return nil, fmt.Errorf("missing encrypted data")
+	}
+	if box.Version != sealedBoxVersion {
+		return nil, fmt.Errorf("unsupported encryption version %d", box.Version)
+	}
+	if len(box.Nonce) != k.aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+	plaintext, err := k.aead.Open(nil, box.Nonce, box.Data, additional)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data: %w", err)
+	}
+	return plaintext, nil
+}
+
+// sealJSON encodes value as JSON and seals it.
+func (k *noteKey) sealJSON(value interface{}, additional []byte) (*SealedBox, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding data: %w", err)
+	}
+	return k.seal(data, additional)
+}
+
+// openJSON opens a box and decodes its JSON into value.
+func (k *noteKey) openJSON(box *SealedBox, additional []byte, value interface{}) error {
+	data, err := k.open(box, additional)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("error parsing decrypted data: %w", err)
+	}
+	return nil
+}
+
+// KDFParams are the Argon2id parameters used to derive a key from a
+// password.
+type KDFParams struct {
+	Salt      []byte `json:"salt"`
+	Time      uint32 `json:"time"`
+	MemoryKiB uint32 `json:"memoryKiB"`
+	Threads   uint8  `json:"threads"`
+}
+
+// newKDFParams returns the default parameters with a fresh salt.
+func newKDFParams() (KDFParams, error) {
+	salt, err := randomBytes(16)
+	if err != nil {
+		return KDFParams{}, err
+	}
+	return KDFParams{Salt: salt, Time: 3, MemoryKiB: 64 * 1024, Threads: 4}, nil
+}
+
+// deriveKey derives a key from a password.
+func (p KDFParams) deriveKey(password string) (*noteKey, error) {
+	return newNoteKey(argon2.IDKey([]byte(password), p.Salt, p.Time, p.MemoryKiB, p.Threads, chacha20poly1305.KeySize))
+}
+
+// encryptionHeader is stored in the store file when encryption is on. The
+// notes are sealed with a random data key, which is itself sealed with the
+// key derived from the password.
+type encryptionHeader struct {
+	KDF KDFParams  `json:"kdf"`
+	Key *SealedBox `json:"key"`
+	// PreviousKey is the data key before a password change. It is kept until
+	// every revision file has been sealed with the new key.
+	PreviousKey *SealedBox `json:"previousKey,omitempty"`
+}
+
+// sealedStore holds the encrypted contents of the store file.
+type sealedStore struct {
+	Jots    []*SealedBox `json:"jots"`
+	Trash   []*SealedBox `json:"trash,omitempty"`
+	Folders *SealedBox   `json:"folders,omitempty"`
+}
+
+// Additional data binding each kind of box to its place in the store.
+var (
+	jotAdditionalData     = []byte("toJot jot")
+	foldersAdditionalData = []byte("toJot folders")
+	dataKeyAdditionalData = []byte("toJot data key")
+)
+
+// revisionsAdditionalData binds a sealed revision file to its jot.
+func revisionsAdditionalData(jotID string) []byte {
+	return []byte("toJot revisions " + jotID)
+}
+
+// newEncryptionHeader creates a header for a new password, sealing dataKey
+// and, if set, previousKey with it.
+func newEncryptionHeader(password string, dataKey, previousKey []byte) (*encryptionHeader, error) {
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	params, err := newKDFParams()
+	if err != nil {
+		return nil, err
+	}
+	passwordKey, err := params.deriveKey(password)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &encryptionHeader{KDF: params}
+	if header.Key, err = passwordKey.seal(dataKey, dataKeyAdditionalData); err != nil {
+		return nil, err
+	}
+	if previousKey != nil {
+		if header.PreviousKey, err = passwordKey.seal(previousKey, dataKeyAdditionalData); err != nil {
+			return nil, err
+		}
+	}
+	return header, nil
+}
+
+// openDataKeys derives the password key and opens the data key and, if
+// present, the previous data key. A wrong password is reported as
+// errWrongPassword.
+func (h *encryptionHeader) openDataKeys(password string) (current, previous []byte, err error) {
+	passwordKey, err := h.KDF.deriveKey(password)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err = passwordKey.open(h.Key, dataKeyAdditionalData)
+	if err != nil {
+		return nil, nil, errWrongPassword
+	}
+	if h.PreviousKey != nil {
+		if previous, err = passwordKey.open(h.PreviousKey, dataKeyAdditionalData); err != nil {
+			return nil, nil, fmt.Errorf("error opening previous key: %w", err)
+		}
+	}
+	return current, previous, nil
+}
+
+// sealStoreFile replaces the jots, trash and folders of file with their
+// sealed form.
+func sealStoreFile(file *storeFile, key *noteKey) error {
+	sealed := &sealedStore{}
+	for _, jot := range file.Jots {
+		box, err := key.sealJSON(jot, jotAdditionalData)
+		if err != nil {
+			return err
+		}
+		sealed.Jots = append(sealed.Jots, box)
+	}
+	for _, jot := range file.Trash {
+		box, err := key.sealJSON(jot, jotAdditionalData)
+		if err != nil {
+			return err
+		}
+		sealed.Trash = append(sealed.Trash, box)
+	}
+	if len(file.Folders) > 0 {
+		box, err := key.sealJSON(file.Folders, foldersAdditionalData)
+		if err != nil {
+			return err
+		}
+		sealed.Folders = box
+	}
+
+	file.Jots, file.Trash, file.Folders = nil, nil, nil
+	file.Sealed = sealed
+	return nil
+}
+
+// openStoreFile replaces the sealed contents of file with the plain jots,
+// trash and folders.
+func openStoreFile(file *storeFile, key *noteKey) error {
+	if file.Sealed == nil {
+		return nil
+	}
+	for _, box := range file.Sealed.Jots {
+		var jot Jot
+		if err := key.openJSON(box, jotAdditionalData, &jot); err != nil {
+			return err
+		}
+		file.Jots = append(file.Jots, &jot)
+	}
+	for _, box := range file.Sealed.Trash {
+		var jot Jot
+		if err := key.openJSON(box, jotAdditionalData, &jot); err != nil {
+			return err
+		}
+		file.Trash = append(file.Trash, &jot)
+	}
+	if file.Sealed.Folders != nil {
+		if err := key.openJSON(file.Sealed.Folders, foldersAdditionalData, &file.Folders); err != nil {
+			return err
+		}
+	}
+	file.Sealed = nil
+	return nil
+}
+
+// EncryptionStatus tells the frontend whether encryption is on and whether
+// the store is locked.
+type EncryptionStatus struct {
+	Encrypted bool `json:"encrypted"`
+	Locked    bool `json:"locked"`
+}
+
+// EncryptionStatus returns whether encryption is on and the store is locked.
+func (s *Store) EncryptionStatus() EncryptionStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return EncryptionStatus{Encrypted: s.encryption != nil, Locked: s.locked}
+}
+
+// EnableEncryption encrypts the store and all revisions with a new password.
+// Keys are derived and revisions resealed without holding the store lock,
+// so reads and saves go on meanwhile.
+func (s *Store) EnableEncryption(password string) error {
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+
+	if s.EncryptionStatus().Encrypted {
+		return fmt.Errorf("encryption is already enabled")
+	}
+	dataKey, err := randomBytes(chacha20poly1305.KeySize)
+	if err != nil {
+		return err
+	}
+	header, err := newEncryptionHeader(password, dataKey, nil)
+	if err != nil {
+		return err
+	}
+	key, err := newNoteKey(dataKey)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.encryption, s.key = header, key
+	if err := s.persist(); err != nil {
+		s.encryption, s.key = nil, nil
+		s.mu.Unlock()
+		return err
+	}
+	s.revisions.SetKeys(key, nil)
+	s.mu.Unlock()
+
+	return s.revisions.Reseal()
+}
+
+// DisableEncryption decrypts the store and all revisions. The revisions are
+// decrypted first, while the store file still holds the data key, so a
+// failure leaves no file sealed with a key that is gone.
+func (s *Store) DisableEncryption(password string) error {
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+
+	header, key, err := s.unlockedHeader()
+	if err != nil {
+		return err
+	}
+	if _, _, err := header.openDataKeys(password); err != nil {
+		return err
+	}
+
+	s.revisions.SetKeys(nil, key)
+	if err := s.revisions.Reseal(); err != nil {
+		s.restoreRevisionKey(key)
+		return err
+	}
+
+	s.mu.Lock()
+	s.encryption, s.key = nil, nil
+	if err := s.persist(); err != nil {
+		s.encryption, s.key = header, key
+		s.mu.Unlock()
+		s.restoreRevisionKey(key)
+		return err
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// restoreRevisionKey seals the revisions with key again after disabling
+// encryption failed.
+func (s *Store) restoreRevisionKey(key *noteKey) {
+	s.revisions.SetKeys(key, nil)
+	if err := s.revisions.Reseal(); err != nil {
+		fmt.Printf("Error sealing revisions again: %v\n", err)
+	}
+}
+
+// ChangePassword re-encrypts the store and all revisions with a new data key
+// sealed under a new password. The old data key stays in the store file
+// until every revision has been re-encrypted, so an interrupted change is
+// finished by the next unlock.
+func (s *Store) ChangePassword(oldPassword, newPassword string) error {
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+
+	current, previousKey, err := s.unlockedHeader()
+	if err != nil {
+		return err
+	}
+	oldKey, _, err := current.openDataKeys(oldPassword)
+	if err != nil {
+		return err
+	}
+	dataKey, err := randomBytes(chacha20poly1305.KeySize)
+	if err != nil {
+		return err
+	}
+	header, err := newEncryptionHeader(newPassword, dataKey, oldKey)
+	if err != nil {
+		return err
+	}
+	key, err := newNoteKey(dataKey)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.encryption, s.key = header, key
+	if err := s.persist(); err != nil {
+		s.encryption, s.key = current, previousKey
+		s.mu.Unlock()
+		return err
+	}
+	s.revisions.SetKeys(key, previousKey)
+	s.mu.Unlock()
+
+	return s.finishKeyChange()
+}
+
+// unlockedHeader returns the encryption header and key of an encrypted store
+// that is unlocked.
+func (s *Store) unlockedHeader() (*encryptionHeader, *noteKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.encryption == nil {
+		return nil, nil, fmt.Errorf("encryption is not enabled")
+	}
+	if s.locked {
+		return nil, nil, errStoreLocked
+	}
+	return s.encryption, s.key, nil
+}
+
+// finishKeyChange re-encrypts the revisions with the current key and then
+// drops the previous key from the store file. The caller must hold keyMu
+// but not mu.
+func (s *Store) finishKeyChange() error {
+	if err := s.revisions.Reseal(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	header := *s.encryption
+	header.PreviousKey = nil
+	s.encryption = &header
+	s.revisions.SetKeys(s.key, nil)
+	return s.persist()
+}
+
+// Unlock opens an encrypted store with its password and loads the jots. The
+// key is derived without holding the store lock.
+func (s *Store) Unlock(password string) error {
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+
+	status := s.EncryptionStatus()
+	if !status.Encrypted || !status.Locked {
+		return nil
+	}
+	file, err := s.readFile()
+	if err != nil {
+		return err
+	}
+	if file.Encryption == nil {
+		return fmt.Errorf("store file is not encrypted")
+	}
+	current, previous, err := file.Encryption.openDataKeys(password)
+	if err != nil {
+		return err
+	}
+	key, err := newNoteKey(current)
+	if err != nil {
+		return err
+	}
+	if err := openStoreFile(&file, key); err != nil {
+		return err
+	}
+	var previousKey *noteKey
+	if previous != nil {
+		if previousKey, err = newNoteKey(previous); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.encryption, s.key, s.locked = file.Encryption, key, false
+	s.loadLocked(file)
+	s.revisions.SetKeys(key, previousKey)
+	s.mu.Unlock()
+
+	if previousKey != nil {
+		if err := s.finishKeyChange(); err != nil {
+			fmt.Printf("Error finishing password change: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// Lock forgets the decrypted jots, the indexes built from them and the key.
+// Locking a store without encryption does nothing. A key change in progress
+// is finished first.
+func (s *Store) Lock() {
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.encryption == nil || s.locked {
+		return
+	}
+	s.key, s.locked = nil, true
+	s.jotKeys = make(map[string]*noteKey)
+	s.loadLocked(storeFile{})
+	s.revisions.SetKeys(nil, nil)
+}
+
+// runAutoLock locks the store once nothing happened for the configured
+// number of minutes. It runs until ctx is done.
+func runAutoLock(ctx context.Context, store *Store, settings *SettingsStore, lastActivity func() time.Time, onLock func()) {
+	ticker := time.NewTicker(autoLockCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		minutes := settings.Get().AutoLockMinutes
+		status := store.EncryptionStatus()
+		if minutes <= 0 || !status.Encrypted || status.Locked {
+			continue
+		}
+		if time.Since(lastActivity()) >= time.Duration(minutes)*time.Minute {
+			store.Lock()
+			onLock()
+		}
+	}
+}
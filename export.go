@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Export formats.
+const (
+	ExportMarkdown = "markdown"
+	ExportHTML     = "html"
+	ExportText     = "text"
+)
+
+// How note links are written in an export. Title replaces a link with the
+// title of the jot it points at; relative turns it into a link to the file
+// the jot is exported to, and is only for exporting every jot to a file of
+// its own.
+const (
+	ExportLinksTitle    = "title"
+	ExportLinksRelative = "relative"
+)
+
+// ExportOptions controls the format of an export.
+type ExportOptions struct {
+	Format string `json:"format"`
+	Links  string `json:"links"`
+	// files are the names of the files the jots are exported to, by id, for
+	// relative links. Links to jots without a file show their title.
+	files map[string]string
+}
+
+// exportExtensions are the file extensions of the export formats.
+var exportExtensions = map[string]string{
+	ExportMarkdown: ".md",
+	ExportHTML:     ".html",
+	ExportText:     ".txt",
+}
+
+// validate fills in defaults and checks the options.
+func (o *ExportOptions) validate() error {
+	if o.Format == "" {
+		o.Format = ExportMarkdown
+	}
+	if o.Links == "" {
+		o.Links = ExportLinksTitle
+	}
+	if _, ok := exportExtensions[o.Format]; !ok {
+		return fmt.Errorf("unknown export format %q", o.Format)
+	}
+	if o.Links != ExportLinksTitle && o.Links != ExportLinksRelative {
+		return fmt.Errorf("unknown link style %q", o.Links)
+	}
+	if o.Links == ExportLinksRelative && o.files == nil {
+		return fmt.Errorf("relative links need every jot exported to its own file")
+	}
+	return nil
+}
+
+// ExportFileName returns the file name an export of the jots is saved as by
+// default.
+func (s *Store) ExportFileName(ids []string, options ExportOptions) string {
+	if err := options.validate(); err != nil {
+		return ""
+	}
+	name := "toJot export"
+	if len(ids) == 1 {
+		if jot, ok := s.Get(ids[0]); ok {
+			name = safeFileName(jot.Title)
+		}
+	}
+	return name + exportExtensions[options.Format]
+}
+
+// Export renders the jots with the given ids, in that order, as a single
+// document. Jots locked with their own password cannot be exported.
+func (s *Store) Export(ids []string, options ExportOptions) (string, error) {
+	if err := options.validate(); err != nil {
+		return "", err
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("no jots to export")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jots := make([]*Jot, 0, len(ids))
+	for _, id := range ids {
+		jot, ok := s.jots[id]
+		if !ok {
+			return "", fmt.Errorf("jot %s not found", id)
+		}
+		if jot.Lock != nil {
+			return "", fmt.Errorf("%w: %s", errJotLocked, jot.Title)
+		}
+		jots = append(jots, jot)
+	}
+
+	title := func(link NoteLink) string {
+		if title, ok := s.titleLocked(link.JotID); ok {
+			return title
+		}
+		return link.Label
+	}
+	href := func(link NoteLink) (string, bool) {
+		file, ok := options.files[link.JotID]
+		if !ok || options.Links != ExportLinksRelative {
+			return "", false
+		}
+		return url.PathEscape(file), true
+	}
+
+	var out strings.Builder
+	switch options.Format {
+	case ExportMarkdown:
+		linkText := func(link NoteLink) string {
+			if target, ok := href(link); ok {
+				return "[" + markdownEscaper.Replace(title(link)) + "](" + target + ")"
+			}
+			return markdownEscaper.Replace(title(link))
+		}
+		for i, jot := range jots {
+			if i > 0 {
+				out.WriteString("\n---\n\n")
+			}
+			out.WriteString("# " + markdownEscaper.Replace(jot.Title) + "\n\n")
+			out.WriteString(RenderMarkdown(jot.Content, linkText))
+		}
+	case ExportText:
+		for i, jot := range jots {
+			if i > 0 {
+				out.WriteString("\n\n")
+			}
+			out.WriteString(jot.Title + "\n" + strings.Repeat("=", max(len([]rune(jot.Title)), 3)) + "\n\n")
+			out.WriteString(RenderText(jot.Content, title))
+		}
+	case ExportHTML:
+		linkHTML := func(link NoteLink) string {
+			if target, ok := href(link); ok {
+				return `<a href="` + html.EscapeString(target) + `">` + html.EscapeString(title(link)) + "</a>"
+			}
+			return html.EscapeString(title(link))
+		}
+		var body strings.Builder
+		for _, jot := range jots {
+			body.WriteString("<article>\n<h1>" + html.EscapeString(jot.Title) + "</h1>\n")
+			body.WriteString(`<p class="meta">` + jot.UpdatedAt.Format(time.DateOnly) + "</p>\n")
+			body.WriteString(RenderHTML(jot.Content, linkHTML))
+			body.WriteString("</article>\n")
+		}
+		documentTitle := "toJot export"
+		if len(jots) == 1 {
+			documentTitle = jots[0].Title
+		}
+		out.WriteString(htmlDocument(documentTitle, body.String()))
+	}
+	return out.String(), nil
+}
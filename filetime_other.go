@@ -0,0 +1,14 @@
+//go:build !darwin && !windows
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// fileCreatedAt returns the creation time of a file. Other platforms do not
+// report it through os.Stat, so the modification time is used.
+func fileCreatedAt(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sort orders for the jots in a folder.
+const (
+	SortByUpdated = "updated"
+	SortByCreated = "created"
+	SortByTitle   = "title"
+	SortManual    = "manual"
+)
+
+// Folder is a notebook that holds jots and other folders.
+type Folder struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	ParentID  string    `json:"parentId,omitempty"`
+	SortOrder string    `json:"sortOrder"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// JotSummary is the part of a jot needed to list it.
+type JotSummary struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	FolderID  string    `json:"folderId,omitempty"`
+	Pinned    bool      `json:"pinned,omitempty"`
+	Favourite bool      `json:"favourite,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FolderTreeNode is a folder with its subfolders and jots. The root node
+// has no folder and holds the jots that are not in any folder.
+type FolderTreeNode struct {
+	Folder   *Folder           `json:"folder"`
+	Children []*FolderTreeNode `json:"children"`
+	Jots     []JotSummary      `json:"jots"`
+}
+
+// summary returns the summary of a jot.
+func (j *Jot) summary() JotSummary {
+	return JotSummary{
+		ID:        j.ID,
+		Title:     j.Title,
+		FolderID:  j.FolderID,
+		Pinned:    j.Pinned,
+		Favourite: j.Favourite,
+		UpdatedAt: j.UpdatedAt,
+	}
+}
+
+// validSortOrder reports whether order is a known sort order.
+func validSortOrder(order string) bool {
+	switch order {
+	case SortByUpdated, SortByCreated, SortByTitle, SortManual:
+		return true
+	}
+	return false
+}
+
+// validateFolderName checks that a folder name can also be used as a
+// directory name.
+func validateFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid folder name: %q", name)
+	}
+	if strings.ContainsAny(name, `/\:*?"<>|`) {
+		return "", fmt.Errorf("folder name %q contains characters that are not allowed in file names", name)
+	}
+	return name, nil
+}
+
+// sortJotsBy sorts jots in the given sort order.
+func sortJotsBy(jots []*Jot, order string) {
+	sort.SliceStable(jots, func(i, j int) bool {
+		a, b := jots[i], jots[j]
+		switch order {
+		case SortByCreated:
+			return a.CreatedAt.After(b.CreatedAt)
+		case SortByTitle:
+			return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+		case SortManual:
+			return a.Position < b.Position
+		default:
+			return a.UpdatedAt.After(b.UpdatedAt)
+		}
+	})
+}
+
+// FolderTree returns all folders as a tree with the jots in each of them.
+func (s *Store) FolderTree() *FolderTreeNode {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	nodes := map[string]*FolderTreeNode{"": {Children: []*FolderTreeNode{}}}
+	for id, folder := range s.folders {
+		clone := *folder
+		nodes[id] = &FolderTreeNode{Folder: &clone, Children: []*FolderTreeNode{}}
+	}
+
+	for _, folder := range s.childFoldersLocked("") {
+		s.buildTreeLocked(nodes, folder)
+	}
+
+	jotsByFolder := make(map[string][]*Jot)
+	for _, jot := range s.jots {
+		folderID := jot.FolderID
+		if _, ok := nodes[folderID]; !ok {
+			folderID = ""
+		}
+		jotsByFolder[folderID] = append(jotsByFolder[folderID], jot)
+	}
+	for id, node := range nodes {
+		jots := jotsByFolder[id]
+		order := SortByUpdated
+		if node.Folder != nil {
+			order = node.Folder.SortOrder
+		}
+		sortJotsBy(jots, order)
+		node.Jots = make([]JotSummary, 0, len(jots))
+		for _, jot := range jots {
+			node.Jots = append(node.Jots, jot.summary())
+		}
+	}
+	return nodes[""]
+}
+
+// buildTreeLocked links a folder and its descendants into their parents.
+func (s *Store) buildTreeLocked(nodes map[string]*FolderTreeNode, folder *Folder) {
+	parent := nodes[folder.ParentID]
+	parent.Children = append(parent.Children, nodes[folder.ID])
+	for _, child := range s.childFoldersLocked(folder.ID) {
+		s.buildTreeLocked(nodes, child)
+	}
+}
+
+// childFoldersLocked returns the direct children of a folder sorted by
+// position.
+func (s *Store) childFoldersLocked(parentID string) []*Folder {
+	var children []*Folder
+	for _, folder := range s.folders {
+		if folder.ParentID == parentID {
+			children = append(children, folder)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].Position == children[j].Position {
+			return children[i].Name < children[j].Name
+		}
+		return children[i].Position < children[j].Position
+	})
+	return children
+}
+
+// uniqueFolderNameLocked returns name, or name with a number appended if a
+// sibling other than id already uses it.
+func (s *Store) uniqueFolderNameLocked(parentID, id, name string) string {
+	taken := make(map[string]struct{})
+	for _, folder := range s.childFoldersLocked(parentID) {
+		if folder.ID != id {
+			taken[strings.ToLower(folder.Name)] = struct{}{}
+		}
+	}
+	unique := name
+	for i := 2; ; i++ {
+		if _, ok := taken[strings.ToLower(unique)]; !ok {
+			return unique
+		}
+		unique = fmt.Sprintf("%s (%d)", name, i)
+	}
+}
+
+// nextFolderPositionLocked returns the position after the last child of a
+// folder.
+func (s *Store) nextFolderPositionLocked(parentID string) int {
+	children := s.childFoldersLocked(parentID)
+	if len(children) == 0 {
+		return 0
+	}
+	return children[len(children)-1].Position + 1
+}
+
+// CreateFolder creates a folder inside parentID, or at the root if parentID
+// is empty.
+func (s *Store) CreateFolder(name, parentID string) (*Folder, error) {
+	name, err := validateFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.folders[parentID]; parentID != "" && !ok {
+		return nil, fmt.Errorf("folder %s not found", parentID)
+	}
+
+	folder := &Folder{
+		ID:        uuid.NewString(),
+		Name:      s.uniqueFolderNameLocked(parentID, "", name),
+		ParentID:  parentID,
+		SortOrder: SortByUpdated,
+		Position:  s.nextFolderPositionLocked(parentID),
+		CreatedAt: time.Now(),
+	}
+	if err := s.writeLocked(storeChange{folders: map[string]*Folder{folder.ID: folder}}); err != nil {
+		return nil, err
+	}
+	clone := *folder
+	return &clone, nil
+}
+
+// RenameFolder changes the name of a folder.
+func (s *Store) RenameFolder(id, name string) (*Folder, error) {
+	name, err := validateFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder, ok := s.folders[id]
+	if !ok {
+		return nil, fmt.Errorf("folder %s not found", id)
+	}
+
+	renamed := *folder
+	renamed.Name = s.uniqueFolderNameLocked(folder.ParentID, id, name)
+	if err := s.writeLocked(storeChange{folders: map[string]*Folder{id: &renamed}}); err != nil {
+		return nil, err
+	}
+	return &renamed, nil
+}
+
+// MoveFolder moves a folder into another folder, or to the root if parentID
+// is empty. A folder cannot be moved into itself or one of its descendants.
+func (s *Store) MoveFolder(id, parentID string) (*Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder, ok := s.folders[id]
+	if !ok {
+		return nil, fmt.Errorf("folder %s not found", id)
+	}
+	if parentID != "" {
+		if _, ok := s.folders[parentID]; !ok {
+			return nil, fmt.Errorf("folder %s not found", parentID)
+		}
+		for ancestor := parentID; ancestor != ""; ancestor = s.folders[ancestor].ParentID {
+			if ancestor == id {
+				return nil, fmt.Errorf("cannot move folder %q into itself", folder.Name)
+			}
+		}
+	}
+	if folder.ParentID == parentID {
+		clone := *folder
+		return &clone, nil
+	}
+
+	moved := *folder
+	moved.ParentID = parentID
+	moved.Name = s.uniqueFolderNameLocked(parentID, id, folder.Name)
+	moved.Position = s.nextFolderPositionLocked(parentID)
+	if err := s.writeLocked(storeChange{folders: map[string]*Folder{id: &moved}}); err != nil {
+		return nil, err
+	}
+	return &moved, nil
+}
+
+// DeleteFolder removes a folder. Its jots and subfolders are moved to its
+// parent.
+func (s *Store) DeleteFolder(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder, ok := s.folders[id]
+	if !ok {
+		return fmt.Errorf("folder %s not found", id)
+	}
+
+	change := storeChange{
+		jots:    make(map[string]*Jot),
+		folders: map[string]*Folder{id: nil},
+	}
+	position := s.nextFolderPositionLocked(folder.ParentID)
+	for _, child := range s.childFoldersLocked(id) {
+		moved := *child
+		moved.ParentID = folder.ParentID
+		moved.Name = s.uniqueFolderNameLocked(folder.ParentID, child.ID, child.Name)
+		moved.Position = position
+		position++
+		change.folders[child.ID] = &moved
+	}
+	for jotID, jot := range s.jots {
+		if jot.FolderID == id {
+			moved := jot.clone()
+			moved.FolderID = folder.ParentID
+			change.jots[jotID] = moved
+		}
+	}
+	return s.writeLocked(change)
+}
+
+// SetFolderSortOrder changes how the jots in a folder are sorted.
+func (s *Store) SetFolderSortOrder(id, order string) (*Folder, error) {
+	if !validSortOrder(order) {
+		return nil, fmt.Errorf("unknown sort order: %q", order)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder, ok := s.folders[id]
+	if !ok {
+		return nil, fmt.Errorf("folder %s not found", id)
+	}
+
+	updated := *folder
+	updated.SortOrder = order
+	if err := s.writeLocked(storeChange{folders: map[string]*Folder{id: &updated}}); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// ReorderFolders sets the order of the subfolders of parentID. Folders not
+// in ids keep their relative order after the listed ones.
+func (s *Store) ReorderFolders(parentID string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	children := s.childFoldersLocked(parentID)
+	ordered := make([]*Folder, 0, len(children))
+	listed := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		folder, ok := s.folders[id]
+		if !ok || folder.ParentID != parentID {
+			return fmt.Errorf("folder %s is not in this folder", id)
+		}
+		if _, ok := listed[id]; !ok {
+			listed[id] = struct{}{}
+			ordered = append(ordered, folder)
+		}
+	}
+	for _, folder := range children {
+		if _, ok := listed[folder.ID]; !ok {
+			ordered = append(ordered, folder)
+		}
+	}
+
+	change := storeChange{folders: make(map[string]*Folder, len(ordered))}
+	for position, folder := range ordered {
+		if folder.Position != position {
+			updated := *folder
+			updated.Position = position
+			change.folders[folder.ID] = &updated
+		}
+	}
+	return s.writeLocked(change)
+}
+
+// nextJotPositionLocked returns the manual position after the last jot in a
+// folder.
+func (s *Store) nextJotPositionLocked(folderID string) int {
+	next := 0
+	for _, jot := range s.jots {
+		if jot.FolderID == folderID && jot.Position >= next {
+			next = jot.Position + 1
+		}
+	}
+	return next
+}
+
+// MoveJots moves jots into a folder, or to the root if folderID is empty.
+// Moved jots go to the end of the manual order of the folder. All jots are
+// moved in one write. It returns the number of jots moved.
+func (s *Store) MoveJots(ids []string, folderID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.folders[folderID]; folderID != "" && !ok {
+		return 0, fmt.Errorf("folder %s not found", folderID)
+	}
+
+	change := storeChange{jots: make(map[string]*Jot, len(ids))}
+	position := s.nextJotPositionLocked(folderID)
+	for _, id := range ids {
+		jot, ok := s.jots[id]
+		if !ok {
+			return 0, fmt.Errorf("jot %s not found", id)
+		}
+		if _, ok := change.jots[id]; !ok && jot.FolderID != folderID {
+			moved := jot.clone()
+			moved.FolderID = folderID
+			moved.Position = position
+			position++
+			change.jots[id] = moved
+		}
+	}
+	if err := s.writeLocked(change); err != nil {
+		return 0, err
+	}
+	return len(change.jots), nil
+}
+
+// ReorderJots sets the manual order of the jots in a folder, as after a
+// drag and drop, and switches the folder to manual sorting. Jots not in ids
+// keep their relative order after the listed ones. The jots outside of any
+// folder are always sorted by their last change and cannot be reordered.
+func (s *Store) ReorderJots(folderID string, ids []string) error {
+	if folderID == "" {
+		return fmt.Errorf("jots outside of a folder cannot be sorted by hand")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder, ok := s.folders[folderID]
+	if !ok {
+		return fmt.Errorf("folder %s not found", folderID)
+	}
+	change := storeChange{jots: make(map[string]*Jot)}
+	if folder.SortOrder != SortManual {
+		updated := *folder
+		updated.SortOrder = SortManual
+		change.folders = map[string]*Folder{folderID: &updated}
+	}
+
+	var inFolder []*Jot
+	for _, jot := range s.jots {
+		if jot.FolderID == folderID {
+			inFolder = append(inFolder, jot)
+		}
+	}
+	sortJotsBy(inFolder, SortManual)
+
+	ordered := make([]*Jot, 0, len(inFolder))
+	listed := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		jot, ok := s.jots[id]
+		if !ok || jot.FolderID != folderID {
+			return fmt.Errorf("jot %s is not in this folder", id)
+		}
+		if _, ok := listed[id]; !ok {
+			listed[id] = struct{}{}
+			ordered = append(ordered, jot)
+		}
+	}
+	for _, jot := range inFolder {
+		if _, ok := listed[jot.ID]; !ok {
+			ordered = append(ordered, jot)
+		}
+	}
+
+	for position, jot := range ordered {
+		if jot.Position != position {
+			updated := jot.clone()
+			updated.Position = position
+			change.jots[jot.ID] = updated
+		}
+	}
+	return s.writeLocked(change)
+}
@@ -6,6 +6,12 @@ import { createPinia } from "pinia";
 import piniaPluginPersistedstate from "pinia-plugin-persistedstate";
 import { router } from "./router";
 import * as jotService from "./services/jotService";
+import { EventsOn } from "../wailsjs/runtime";
+import {
+  ReportActivity,
+  TakeLaunchCapture,
+  TakeLaunchJot,
+} from "../wailsjs/go/main/App";
 const pinia = createPinia();
 pinia.use(piniaPluginPersistedstate);
 
@@ -15,6 +21,63 @@ app.use(pinia);
 app.use(router);
 app.mount("#app");
 
+jotService.subscribeToBackendChanges();
+
+EventsOn("store:locked", () => {
+  router.push({
+    path: "/unlock",
+    query: { redirect: router.currentRoute.value.fullPath },
+  });
+});
+
+// Show the jot a launch of toJot created or changed, such as a file opened
+// with toJot or a `toJot new` sent on to this window.
+const openJot = async (id: string) => {
+  try {
+    await jotService.loadBackendJot(id);
+    router.push(`/jot/${id}`);
+  } catch (error) {
+    console.error("Failed to open jot:", error);
+  }
+};
+EventsOn("jot:open", openJot);
+// Show a page clipped from the browser through the local API.
+EventsOn("jot:clipped", (jot: { id: string }) => openJot(jot.id));
+TakeLaunchJot().then((id) => {
+  if (id) {
+    openJot(id);
+  }
+});
+
+// Switch to quick capture for `toJot capture` or a tojot://capture link, and
+// go back to where the window was once it is done.
+let beforeCapture = "/";
+const openCapture = () => {
+  if (router.currentRoute.value.path !== "/capture") {
+    beforeCapture = router.currentRoute.value.fullPath;
+  }
+  router.push("/capture");
+};
+EventsOn("capture:open", openCapture);
+EventsOn("capture:closed", () => router.replace(beforeCapture));
+TakeLaunchCapture().then((capture) => {
+  if (capture) {
+    openCapture();
+  }
+});
+
+// Report user activity for auto-lock, at most every few seconds.
+let lastActivityReport = 0;
+const reportActivity = () => {
+  const now = Date.now();
+  if (now - lastActivityReport > 5000) {
+    lastActivityReport = now;
+    ReportActivity();
+  }
+};
+window.addEventListener("keydown", reportActivity);
+window.addEventListener("pointerdown", reportActivity);
+
 // --- Temporary addition for testing ---
 if (import.meta.env.DEV) {
   // Only expose in development mode
@@ -1,13 +1,30 @@
 import { createWebHistory, createRouter } from "vue-router";
 
 import JotView from "./views/JotView.vue";
+import UnlockView from "./views/UnlockView.vue";
+import CaptureView from "./views/CaptureView.vue";
+import { GetEncryptionStatus } from "../wailsjs/go/main/App";
 
 const routes = [
   { path: "/", component: JotView },
   { path: "/jot/:id", component: JotView },
+  { path: "/unlock", component: UnlockView },
+  { path: "/capture", component: CaptureView },
 ];
 
 export const router = createRouter({
   history: createWebHistory(),
   routes,
 });
+
+// Keep every other view behind the unlock screen while the store is locked.
+router.beforeEach(async (to) => {
+  if (to.path === "/unlock") {
+    return true;
+  }
+  const status = await GetEncryptionStatus();
+  if (status.locked) {
+    return { path: "/unlock", query: { redirect: to.fullPath } };
+  }
+  return true;
+});
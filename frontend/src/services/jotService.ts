@@ -5,6 +5,107 @@ import { liveQuery } from "dexie";
 import { useObservable } from "@vueuse/rxjs";
 import { from } from "rxjs";
 import { v4 as uuidv4 } from "uuid";
+import {
+  SaveJot,
+  ConfirmQuit,
+  DeleteJot,
+  GetAllJots,
+  GetJot,
+  GetEncryptionStatus,
+  GetBrowserStoreMigration,
+  ImportFromBrowserStore,
+} from "../../wailsjs/go/main/App";
+import type { main } from "../../wailsjs/go/models";
+import { EventsOn } from "../../wailsjs/runtime";
+
+/**
+ * Mirrors a Jot into the Go store so backend indexes (links etc.) stay in sync.
+ * The Go store computes the text of the jot itself, which replaces the local
+ * copy so search matches what is stored. Failures are logged and do not
+ * affect the local database.
+ * @param jot The Jot as stored in Dexie.
+ */
+function mirrorSave(jot: Jot): void {
+  SaveJot(jot as unknown as main.Jot)
+    .then(async (saved) => {
+      if (saved.textContent !== jot.textContent) {
+        await db.jots.update(jot.id, { textContent: saved.textContent });
+      }
+    })
+    .catch((error) => {
+      console.error("Failed to mirror jot to Go store:", error);
+    });
+}
+
+/**
+ * Removes a Jot from the Go store.
+ * @param id The ID of the deleted Jot.
+ */
+function mirrorDelete(id: string): void {
+  DeleteJot(id).catch((error) => {
+    console.error("Failed to delete jot from Go store:", error);
+  });
+}
+
+/**
+ * Listens for jots changed on the Go side (e.g. a restored revision) and
+ * writes them to Dexie, so the reactive list picks them up.
+ */
+export function subscribeToBackendChanges(): void {
+  EventsOn("jot:changed", async (jot: main.Jot) => {
+    await db.jots.put({
+      id: jot.id,
+      title: jot.title,
+      content: jot.content as unknown as JSONContent,
+      textContent: jot.textContent,
+      createdAt: new Date(jot.createdAt),
+      updatedAt: new Date(jot.updatedAt),
+    });
+  });
+  // The backend repairs saved content the editor could not render; the
+  // repaired jot arrives as jot:changed.
+  EventsOn(
+    "jot:repaired",
+    (repair: { id: string; problems: { path: string; problem: string }[] }) => {
+      console.warn(`Repaired content of jot ${repair.id}:`, repair.problems);
+    },
+  );
+  EventsOn("jot:deleted", async (id: string) => {
+    await db.jots.delete(id);
+  });
+  // Decrypted jots must not stay in the browser database while locked,
+  // including those left behind when the app was closed unlocked.
+  EventsOn("store:locked", async () => {
+    await db.jots.clear();
+  });
+  // Decrypted jots are cleared before quitting too; the window waits for
+  // the clear before it closes.
+  EventsOn("app:quitting", async () => {
+    try {
+      await db.jots.clear();
+    } finally {
+      ConfirmQuit();
+    }
+  });
+  GetEncryptionStatus().then(async (status) => {
+    if (status.locked) {
+      await db.jots.clear();
+    }
+  });
+  EventsOn("store:unlocked", async () => {
+    const jots = await GetAllJots();
+    await db.jots.bulkPut(
+      jots.map((jot) => ({
+        id: jot.id,
+        title: jot.title,
+        content: jot.content as unknown as JSONContent,
+        textContent: jot.textContent,
+        createdAt: new Date(jot.createdAt),
+        updatedAt: new Date(jot.updatedAt),
+      })),
+    );
+  });
+}
 
 /**
  * Adds a new Jot to the database and updates the search index.
@@ -30,6 +131,7 @@ export async function addJot(
   await db.transaction("rw", db.jots, async () => {
     await db.jots.add(newJot);
   });
+  mirrorSave(newJot);
 
   return newJot;
 }
@@ -74,6 +176,9 @@ export async function updateJot(
 
   // Return the full updated jot
   const updatedJotResult = await db.jots.get(id); // ID is known to be valid here
+  if (updatedJotResult) {
+    mirrorSave(updatedJotResult);
+  }
   return updatedJotResult ?? null;
 }
 
@@ -85,6 +190,7 @@ export async function deleteJot(id: string): Promise<void> {
   await db.transaction("rw", db.jots, async () => {
     await db.jots.delete(id);
   });
+  mirrorDelete(id);
 }
 
 /**
@@ -103,6 +209,23 @@ export async function getJotById(
   return db.jots.get(id);
 }
 
+/**
+ * Copies a jot from the Go store into Dexie, for jots created outside the
+ * window, such as by a later launch of toJot, before they are opened.
+ * @param id The ID of the Jot.
+ */
+export async function loadBackendJot(id: string): Promise<void> {
+  const jot = await GetJot(id);
+  await db.jots.put({
+    id: jot.id,
+    title: jot.title,
+    content: jot.content as unknown as JSONContent,
+    textContent: jot.textContent,
+    createdAt: new Date(jot.createdAt),
+    updatedAt: new Date(jot.updatedAt),
+  });
+}
+
 /**
  * Provides a reactive list of all Jots, sorted by updated date (descending).
  * Uses Dexie's liveQuery and @vueuse/rxjs for Vue reactivity.
@@ -121,6 +244,43 @@ export async function getLatestJot(): Promise<Jot | undefined> {
   return db.jots.orderBy("updatedAt").reverse().first();
 }
 
+/** Number of jots sent to the Go store per migration chunk. */
+const MIGRATION_CHUNK_SIZE = 200;
+
+/**
+ * Copies every Jot in Dexie into the Go store, in chunks. The Go side records
+ * how many chunks it applied, so an interrupted migration continues where it
+ * stopped; sending a chunk twice does not create duplicates.
+ */
+export async function migrateToGoStore(): Promise<void> {
+  const status = await GetEncryptionStatus();
+  if (status.locked) {
+    return;
+  }
+  const migration = await GetBrowserStoreMigration();
+  if (migration?.completedAt) {
+    return;
+  }
+
+  const jots = await db.jots.toArray();
+  const total = Math.max(1, Math.ceil(jots.length / MIGRATION_CHUNK_SIZE));
+  const start = migration?.total === total ? migration.chunks : 0;
+  for (let index = start; index < total; index++) {
+    const chunk = jots.slice(
+      index * MIGRATION_CHUNK_SIZE,
+      (index + 1) * MIGRATION_CHUNK_SIZE,
+    );
+    const report = await ImportFromBrowserStore({
+      index,
+      total,
+      jots: chunk,
+    } as unknown as main.BrowserStoreChunk);
+    for (const skipped of report.skipped) {
+      console.warn(`Jot ${skipped.path} was not migrated: ${skipped.reason}`);
+    }
+  }
+}
+
 // --- Dummy Data Utilities ---
 
 const DUMMY_JOT_PREFIX = "[TEST_DATA] ";
@@ -194,6 +354,7 @@ export async function clearDummyJots(): Promise<void> {
 
     // Use Dexie's bulkDelete for efficiency
     await db.jots.bulkDelete(dummyJotIds);
+    dummyJotIds.forEach(mirrorDelete);
 
     console.log("Successfully cleared dummy jots.");
 
@@ -72,6 +72,14 @@ export const useJotStore = defineStore(
       localStorage.setItem("dexieMigrationCompleted", "true");
     };
 
+    const migrateToGoStore = async (): Promise<void> => {
+      try {
+        await jotService.migrateToGoStore();
+      } catch (error) {
+        console.error("Failed to migrate jots to the Go store:", error);
+      }
+    };
+
     const createJot = async (
       title: string = "Untitled Jot",
       content?: JSONContent,
@@ -248,6 +256,7 @@ export const useJotStore = defineStore(
 
       // Actions
       migrateJots,
+      migrateToGoStore,
       createJot,
       updateJot,
       deleteJot,
@@ -1,6 +1,149 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {main} from '../models';
+
+export function CancelCapture():Promise<void>;
+
+export function Capture(arg1:string,arg2:boolean):Promise<main.Jot>;
+
+export function ChangePassword(arg1:string,arg2:string):Promise<void>;
 
 export function CheckForUpdates():Promise<string>;
 
+export function ChooseBackupDirectory():Promise<main.Settings>;
+
+export function ConfirmQuit():Promise<void>;
+
+export function CopyJotLink(arg1:string):Promise<string>;
+
+export function CreateBackup(arg1:string):Promise<main.BackupManifest>;
+
+export function CreateFolder(arg1:string,arg2:string):Promise<main.Folder>;
+
+export function DeleteFolder(arg1:string):Promise<void>;
+
+export function DeleteJot(arg1:string):Promise<Array<main.BrokenLink>>;
+
+export function DiffRevisions(arg1:string,arg2:string,arg3:string):Promise<Array<main.DiffLine>>;
+
+export function DisableEncryption(arg1:string):Promise<void>;
+
 export function DownloadAndInstallUpdate():Promise<string>;
+
+export function EmptyTrash():Promise<number>;
+
+export function EnableEncryption(arg1:string):Promise<void>;
+
+export function ExportJots(arg1:Array<string>,arg2:main.ExportOptions):Promise<string>;
+
+export function FindBrokenLinks():Promise<Array<main.BrokenLink>>;
+
+export function GetAPIStatus():Promise<main.APIStatus>;
+
+export function GetAllJots():Promise<Array<main.Jot>>;
+
+export function GetBacklinks(arg1:string):Promise<Array<main.Jot>>;
+
+export function GetBrowserStoreMigration():Promise<main.Migration>;
+
+export function GetDailyNotes(arg1:string,arg2:string):Promise<Array<main.DailyNote>>;
+
+export function GetEncryptionStatus():Promise<main.EncryptionStatus>;
+
+export function GetFolderTree():Promise<main.FolderTreeNode>;
+
+export function GetJot(arg1:string):Promise<main.Jot>;
+
+export function GetLinkGraph():Promise<main.LinkGraph>;
+
+export function GetRevision(arg1:string,arg2:string):Promise<main.Revision>;
+
+export function GetSettings():Promise<main.Settings>;
+
+export function ImportENEX(arg1:string,arg2:boolean):Promise<main.ImportReport>;
+
+export function ImportFromBrowserStore(arg1:main.BrowserStoreChunk):Promise<main.ImportReport>;
+
+export function ImportKeep(arg1:string,arg2:boolean):Promise<main.ImportReport>;
+
+export function ImportMarkdownFolder(arg1:string):Promise<main.ImportReport>;
+
+export function ListFavourites():Promise<Array<main.JotSummary>>;
+
+export function ListJots():Promise<Array<main.JotSummary>>;
+
+export function ListJotsByTag(arg1:string):Promise<Array<main.Jot>>;
+
+export function ListRevisions(arg1:string):Promise<Array<main.RevisionInfo>>;
+
+export function ListTags():Promise<Array<main.TagInfo>>;
+
+export function ListTrash():Promise<Array<main.Jot>>;
+
+export function Lock():Promise<void>;
+
+export function LockJot(arg1:string,arg2:string):Promise<main.Jot>;
+
+export function MoveFolder(arg1:string,arg2:string):Promise<main.Folder>;
+
+export function MoveJots(arg1:Array<string>,arg2:string):Promise<number>;
+
+export function OpenDailyNote(arg1:string):Promise<main.Jot>;
+
+export function OpenNextDailyNote(arg1:string):Promise<main.Jot>;
+
+export function OpenPreviousDailyNote(arg1:string):Promise<main.Jot>;
+
+export function OpenTodayNote():Promise<main.Jot>;
+
+export function PinJot(arg1:string):Promise<main.Jot>;
+
+export function RegenerateAPIToken():Promise<string>;
+
+export function ReindexAll():Promise<number>;
+
+export function RelockJot(arg1:string):Promise<void>;
+
+export function RemoveJotLock(arg1:string,arg2:string):Promise<main.Jot>;
+
+export function RenameFolder(arg1:string,arg2:string):Promise<main.Folder>;
+
+export function RenameTag(arg1:string,arg2:string):Promise<number>;
+
+export function ReorderFolders(arg1:string,arg2:Array<string>):Promise<void>;
+
+export function ReorderJots(arg1:string,arg2:Array<string>):Promise<void>;
+
+export function ReorderPinned(arg1:Array<string>):Promise<void>;
+
+export function ReportActivity():Promise<void>;
+
+export function RestoreBackup(arg1:string,arg2:boolean,arg3:string):Promise<main.RestoreReport>;
+
+export function RestoreFromTrash(arg1:string):Promise<main.Jot>;
+
+export function RestoreRevision(arg1:string,arg2:string):Promise<main.Jot>;
+
+export function SaveExport(arg1:Array<string>,arg2:main.ExportOptions):Promise<string>;
+
+export function SaveJot(arg1:main.Jot):Promise<main.Jot>;
+
+export function SavePDF(arg1:Array<string>,arg2:main.PDFOptions):Promise<string>;
+
+export function SetFavourite(arg1:string,arg2:boolean):Promise<main.Jot>;
+
+export function SetFolderSortOrder(arg1:string,arg2:string):Promise<main.Folder>;
+
+export function TakeLaunchCapture():Promise<boolean>;
+
+export function TakeLaunchJot():Promise<string>;
+
+export function UnlinkBrokenLinks(arg1:string):Promise<number>;
+
+export function Unlock(arg1:string):Promise<void>;
+
+export function UnlockJot(arg1:string,arg2:string):Promise<main.Jot>;
+
+export function UnpinJot(arg1:string):Promise<main.Jot>;
+
+export function UpdateSettings(arg1:main.Settings):Promise<void>;
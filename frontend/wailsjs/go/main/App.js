@@ -2,10 +2,294 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function CancelCapture() {
+  return window['go']['main']['App']['CancelCapture']();
+}
+
+export function Capture(arg1, arg2) {
+  return window['go']['main']['App']['Capture'](arg1, arg2);
+}
+
+export function ChangePassword(arg1, arg2) {
+  return window['go']['main']['App']['ChangePassword'](arg1, arg2);
+}
+
 export function CheckForUpdates() {
   return window['go']['main']['App']['CheckForUpdates']();
 }
 
+export function ChooseBackupDirectory() {
+  return window['go']['main']['App']['ChooseBackupDirectory']();
+}
+
+export function ConfirmQuit() {
+  return window['go']['main']['App']['ConfirmQuit']();
+}
+
+export function CopyJotLink(arg1) {
+  return window['go']['main']['App']['CopyJotLink'](arg1);
+}
+
+export function CreateBackup(arg1) {
+  return window['go']['main']['App']['CreateBackup'](arg1);
+}
+
+export function CreateFolder(arg1, arg2) {
+  return window['go']['main']['App']['CreateFolder'](arg1, arg2);
+}
+
+export function DeleteFolder(arg1) {
+  return window['go']['main']['App']['DeleteFolder'](arg1);
+}
+
+export function DeleteJot(arg1) {
+  return window['go']['main']['App']['DeleteJot'](arg1);
+}
+
+export function DiffRevisions(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DiffRevisions'](arg1, arg2, arg3);
+}
+
+export function DisableEncryption(arg1) {
+  return window['go']['main']['App']['DisableEncryption'](arg1);
+}
+
 export function DownloadAndInstallUpdate() {
   return window['go']['main']['App']['DownloadAndInstallUpdate']();
 }
+
+export function EmptyTrash() {
+  return window['go']['main']['App']['EmptyTrash']();
+}
+
+export function EnableEncryption(arg1) {
+  return window['go']['main']['App']['EnableEncryption'](arg1);
+}
+
+export function ExportJots(arg1, arg2) {
+  return window['go']['main']['App']['ExportJots'](arg1, arg2);
+}
+
+export function FindBrokenLinks() {
+  return window['go']['main']['App']['FindBrokenLinks']();
+}
+
+export function GetAPIStatus() {
+  return window['go']['main']['App']['GetAPIStatus']();
+}
+
+export function GetAllJots() {
+  return window['go']['main']['App']['GetAllJots']();
+}
+
+export function GetBacklinks(arg1) {
+  return window['go']['main']['App']['GetBacklinks'](arg1);
+}
+
+export function GetBrowserStoreMigration() {
+  return window['go']['main']['App']['GetBrowserStoreMigration']();
+}
+
+export function GetDailyNotes(arg1, arg2) {
+  return window['go']['main']['App']['GetDailyNotes'](arg1, arg2);
+}
+
+export function GetEncryptionStatus() {
+  return window['go']['main']['App']['GetEncryptionStatus']();
+}
+
+export function GetFolderTree() {
+  return window['go']['main']['App']['GetFolderTree']();
+}
+
+export function GetJot(arg1) {
+  return window['go']['main']['App']['GetJot'](arg1);
+}
+
+export function GetLinkGraph() {
+  return window['go']['main']['App']['GetLinkGraph']();
+}
+
+export function GetRevision(arg1, arg2) {
+  return window['go']['main']['App']['GetRevision'](arg1, arg2);
+}
+
+export function GetSettings() {
+  return window['go']['main']['App']['GetSettings']();
+}
+
+export function ImportENEX(arg1, arg2) {
+  return window['go']['main']['App']['ImportENEX'](arg1, arg2);
+}
+
+export function ImportFromBrowserStore(arg1) {
+  return window['go']['main']['App']['ImportFromBrowserStore'](arg1);
+}
+
+export function ImportKeep(arg1, arg2) {
+  return window['go']['main']['App']['ImportKeep'](arg1, arg2);
+}
+
+export function ImportMarkdownFolder(arg1) {
+  return window['go']['main']['App']['ImportMarkdownFolder'](arg1);
+}
+
+export function ListFavourites() {
+  return window['go']['main']['App']['ListFavourites']();
+}
+
+export function ListJots() {
+  return window['go']['main']['App']['ListJots']();
+}
+
+export function ListJotsByTag(arg1) {
+  return window['go']['main']['App']['ListJotsByTag'](arg1);
+}
+
+export function ListRevisions(arg1) {
+  return window['go']['main']['App']['ListRevisions'](arg1);
+}
+
+export function ListTags() {
+  return window['go']['main']['App']['ListTags']();
+}
+
+export function ListTrash() {
+  return window['go']['main']['App']['ListTrash']();
+}
+
+export function Lock() {
+  return window['go']['main']['App']['Lock']();
+}
+
+export function LockJot(arg1, arg2) {
+  return window['go']['main']['App']['LockJot'](arg1, arg2);
+}
+
+export function MoveFolder(arg1, arg2) {
+  return window['go']['main']['App']['MoveFolder'](arg1, arg2);
+}
+
+export function MoveJots(arg1, arg2) {
+  return window['go']['main']['App']['MoveJots'](arg1, arg2);
+}
+
+export function OpenDailyNote(arg1) {
+  return window['go']['main']['App']['OpenDailyNote'](arg1);
+}
+
+export function OpenNextDailyNote(arg1) {
+  return window['go']['main']['App']['OpenNextDailyNote'](arg1);
+}
+
+export function OpenPreviousDailyNote(arg1) {
+  return window['go']['main']['App']['OpenPreviousDailyNote'](arg1);
+}
+
+export function OpenTodayNote() {
+  return window['go']['main']['App']['OpenTodayNote']();
+}
+
+export function PinJot(arg1) {
+  return window['go']['main']['App']['PinJot'](arg1);
+}
+
+export function RegenerateAPIToken() {
+  return window['go']['main']['App']['RegenerateAPIToken']();
+}
+
+export function ReindexAll() {
+  return window['go']['main']['App']['ReindexAll']();
+}
+
+export function RelockJot(arg1) {
+  return window['go']['main']['App']['RelockJot'](arg1);
+}
+
+export function RemoveJotLock(arg1, arg2) {
+  return window['go']['main']['App']['RemoveJotLock'](arg1, arg2);
+}
+
+export function RenameFolder(arg1, arg2) {
+  return window['go']['main']['App']['RenameFolder'](arg1, arg2);
+}
+
+export function RenameTag(arg1, arg2) {
+  return window['go']['main']['App']['RenameTag'](arg1, arg2);
+}
+
+export function ReorderFolders(arg1, arg2) {
+  return window['go']['main']['App']['ReorderFolders'](arg1, arg2);
+}
+
+export function ReorderJots(arg1, arg2) {
+  return window['go']['main']['App']['ReorderJots'](arg1, arg2);
+}
+
+export function ReorderPinned(arg1) {
+  return window['go']['main']['App']['ReorderPinned'](arg1);
+}
+
+export function ReportActivity() {
+  return window['go']['main']['App']['ReportActivity']();
+}
+
+export function RestoreBackup(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RestoreBackup'](arg1, arg2, arg3);
+}
+
+export function RestoreFromTrash(arg1) {
+  return window['go']['main']['App']['RestoreFromTrash'](arg1);
+}
+
+export function RestoreRevision(arg1, arg2) {
+  return window['go']['main']['App']['RestoreRevision'](arg1, arg2);
+}
+
+export function SaveExport(arg1, arg2) {
+  return window['go']['main']['App']['SaveExport'](arg1, arg2);
+}
+
+export function SaveJot(arg1) {
+  return window['go']['main']['App']['SaveJot'](arg1);
+}
+
+export function SavePDF(arg1, arg2) {
+  return window['go']['main']['App']['SavePDF'](arg1, arg2);
+}
+
+export function SetFavourite(arg1, arg2) {
+  return window['go']['main']['App']['SetFavourite'](arg1, arg2);
+}
+
+export function SetFolderSortOrder(arg1, arg2) {
+  return window['go']['main']['App']['SetFolderSortOrder'](arg1, arg2);
+}
+
+export function TakeLaunchCapture() {
+  return window['go']['main']['App']['TakeLaunchCapture']();
+}
+
+export function TakeLaunchJot() {
+  return window['go']['main']['App']['TakeLaunchJot']();
+}
+
+export function UnlinkBrokenLinks(arg1) {
+  return window['go']['main']['App']['UnlinkBrokenLinks'](arg1);
+}
+
+export function Unlock(arg1) {
+  return window['go']['main']['App']['Unlock'](arg1);
+}
+
+export function UnlockJot(arg1, arg2) {
+  return window['go']['main']['App']['UnlockJot'](arg1, arg2);
+}
+
+export function UnpinJot(arg1) {
+  return window['go']['main']['App']['UnpinJot'](arg1);
+}
+
+export function UpdateSettings(arg1) {
+  return window['go']['main']['App']['UpdateSettings'](arg1);
+}
@@ -0,0 +1,885 @@
+export namespace main {
+	
+	export class APIStatus {
+	    enabled: boolean;
+	    running: boolean;
+	    address: string;
+	    token: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.running = source["running"];
+	        this.address = source["address"];
+	        this.token = source["token"];
+	        this.error = source["error"];
+	    }
+	}
+	export class BackupManifest {
+	    formatVersion: number;
+	    appVersion: string;
+	    schemaVersion: number;
+	    // Go type: time
+	    createdAt: any;
+	    jotCount: number;
+	    encrypted?: boolean;
+	    checksums: Record<string, string>;
+	    sizes?: Record<string, number>;
+	    attachments: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupManifest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.formatVersion = source["formatVersion"];
+	        this.appVersion = source["appVersion"];
+	        this.schemaVersion = source["schemaVersion"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.jotCount = source["jotCount"];
+	        this.encrypted = source["encrypted"];
+	        this.checksums = source["checksums"];
+	        this.sizes = source["sizes"];
+	        this.attachments = source["attachments"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BrokenLink {
+	    sourceId: string;
+	    sourceTitle: string;
+	    targetId: string;
+	    label: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BrokenLink(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sourceId = source["sourceId"];
+	        this.sourceTitle = source["sourceTitle"];
+	        this.targetId = source["targetId"];
+	        this.label = source["label"];
+	    }
+	}
+	export class BrowserJot {
+	    id: string;
+	    title: string;
+	    content: any;
+	    // Go type: time
+	    createdAt: any;
+	    // Go type: time
+	    updatedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new BrowserJot(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.content = source["content"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BrowserStoreChunk {
+	    index: number;
+	    total: number;
+	    jots: BrowserJot[];
+	
+	    static createFrom(source: any = {}) {
+	        return new BrowserStoreChunk(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.total = source["total"];
+	        this.jots = this.convertValues(source["jots"], BrowserJot);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DailyNote {
+	    date: string;
+	    id: string;
+	    title: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DailyNote(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.date = source["date"];
+	        this.id = source["id"];
+	        this.title = source["title"];
+	    }
+	}
+	export class DiffLine {
+	    op: string;
+	    text: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiffLine(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.op = source["op"];
+	        this.text = source["text"];
+	    }
+	}
+	export class EncryptionStatus {
+	    encrypted: boolean;
+	    locked: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new EncryptionStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.encrypted = source["encrypted"];
+	        this.locked = source["locked"];
+	    }
+	}
+	export class ExportOptions {
+	    format: string;
+	    links: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ExportOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.format = source["format"];
+	        this.links = source["links"];
+	    }
+	}
+	export class Folder {
+	    id: string;
+	    name: string;
+	    parentId?: string;
+	    sortOrder: string;
+	    position: number;
+	    // Go type: time
+	    createdAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Folder(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.parentId = source["parentId"];
+	        this.sortOrder = source["sortOrder"];
+	        this.position = source["position"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class JotSummary {
+	    id: string;
+	    title: string;
+	    folderId?: string;
+	    pinned?: boolean;
+	    favourite?: boolean;
+	    // Go type: time
+	    updatedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new JotSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.folderId = source["folderId"];
+	        this.pinned = source["pinned"];
+	        this.favourite = source["favourite"];
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FolderTreeNode {
+	    folder?: Folder;
+	    children: FolderTreeNode[];
+	    jots: JotSummary[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FolderTreeNode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.folder = this.convertValues(source["folder"], Folder);
+	        this.children = this.convertValues(source["children"], FolderTreeNode);
+	        this.jots = this.convertValues(source["jots"], JotSummary);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SkippedFile {
+	    path: string;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SkippedFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class ImportReport {
+	    dryRun: boolean;
+	    imported: number;
+	    updated?: number;
+	    folders: number;
+	    ids: string[];
+	    skipped: SkippedFile[];
+	    repaired: SkippedFile[];
+	    unresolvedLinks: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.dryRun = source["dryRun"];
+	        this.imported = source["imported"];
+	        this.updated = source["updated"];
+	        this.folders = source["folders"];
+	        this.ids = source["ids"];
+	        this.skipped = this.convertValues(source["skipped"], SkippedFile);
+	        this.repaired = this.convertValues(source["repaired"], SkippedFile);
+	        this.unresolvedLinks = source["unresolvedLinks"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class JotSource {
+	    url: string;
+	    title?: string;
+	    // Go type: time
+	    clippedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new JotSource(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.title = source["title"];
+	        this.clippedAt = this.convertValues(source["clippedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SealedBox {
+	    v: number;
+	    kid?: string;
+	    nonce: number[];
+	    data: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SealedBox(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.v = source["v"];
+	        this.kid = source["kid"];
+	        this.nonce = source["nonce"];
+	        this.data = source["data"];
+	    }
+	}
+	export class KDFParams {
+	    salt: number[];
+	    time: number;
+	    memoryKiB: number;
+	    threads: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new KDFParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.salt = source["salt"];
+	        this.time = source["time"];
+	        this.memoryKiB = source["memoryKiB"];
+	        this.threads = source["threads"];
+	    }
+	}
+	export class JotLock {
+	    version: number;
+	    kdf: KDFParams;
+	    box?: SealedBox;
+	
+	    static createFrom(source: any = {}) {
+	        return new JotLock(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.kdf = this.convertValues(source["kdf"], KDFParams);
+	        this.box = this.convertValues(source["box"], SealedBox);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Mark {
+	    type: string;
+	    attrs?: Record<string, any>;
+	
+	    static createFrom(source: any = {}) {
+	        return new Mark(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.attrs = source["attrs"];
+	    }
+	}
+	export class Node {
+	    type: string;
+	    attrs?: Record<string, any>;
+	    content?: Node[];
+	    marks?: Mark[];
+	    text?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Node(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.attrs = source["attrs"];
+	        this.content = this.convertValues(source["content"], Node);
+	        this.marks = this.convertValues(source["marks"], Mark);
+	        this.text = source["text"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Jot {
+	    id: string;
+	    title: string;
+	    content?: Node;
+	    textContent: string;
+	    // Go type: time
+	    createdAt: any;
+	    // Go type: time
+	    updatedAt: any;
+	    folderId?: string;
+	    position?: number;
+	    pinned?: boolean;
+	    pinOrder?: number;
+	    favourite?: boolean;
+	    lock?: JotLock;
+	    // Go type: time
+	    deletedAt?: any;
+	    source?: JotSource;
+	    dailyDate?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Jot(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.content = this.convertValues(source["content"], Node);
+	        this.textContent = source["textContent"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	        this.folderId = source["folderId"];
+	        this.position = source["position"];
+	        this.pinned = source["pinned"];
+	        this.pinOrder = source["pinOrder"];
+	        this.favourite = source["favourite"];
+	        this.lock = this.convertValues(source["lock"], JotLock);
+	        this.deletedAt = this.convertValues(source["deletedAt"], null);
+	        this.source = this.convertValues(source["source"], JotSource);
+	        this.dailyDate = source["dailyDate"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	
+	export class LinkGraphEdge {
+	    source: string;
+	    target: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LinkGraphEdge(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = source["source"];
+	        this.target = source["target"];
+	    }
+	}
+	export class LinkGraphNode {
+	    id: string;
+	    title: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LinkGraphNode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	    }
+	}
+	export class LinkGraph {
+	    nodes: LinkGraphNode[];
+	    edges: LinkGraphEdge[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LinkGraph(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.nodes = this.convertValues(source["nodes"], LinkGraphNode);
+	        this.edges = this.convertValues(source["edges"], LinkGraphEdge);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	export class Migration {
+	    // Go type: time
+	    startedAt: any;
+	    // Go type: time
+	    completedAt?: any;
+	    total: number;
+	    chunks: number;
+	    imported: number;
+	    updated: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Migration(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.completedAt = this.convertValues(source["completedAt"], null);
+	        this.total = source["total"];
+	        this.chunks = source["chunks"];
+	        this.imported = source["imported"];
+	        this.updated = source["updated"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class PDFOptions {
+	    pageSize: string;
+	    margin: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PDFOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pageSize = source["pageSize"];
+	        this.margin = source["margin"];
+	    }
+	}
+	export class RestoreReport {
+	    restored: number;
+	    skipped: number;
+	    removed: number;
+	    repaired: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RestoreReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.restored = source["restored"];
+	        this.skipped = source["skipped"];
+	        this.removed = source["removed"];
+	        this.repaired = source["repaired"];
+	    }
+	}
+	export class Revision {
+	    id: string;
+	    jotId: string;
+	    title: string;
+	    content?: Node;
+	    textContent: string;
+	    // Go type: time
+	    createdAt: any;
+	    // Go type: time
+	    startedAt?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Revision(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.jotId = source["jotId"];
+	        this.title = source["title"];
+	        this.content = this.convertValues(source["content"], Node);
+	        this.textContent = source["textContent"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RevisionInfo {
+	    id: string;
+	    jotId: string;
+	    title: string;
+	    // Go type: time
+	    createdAt: any;
+	    textLength: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RevisionInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.jotId = source["jotId"];
+	        this.title = source["title"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.textLength = source["textLength"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class Settings {
+	    trashRetentionDays: number;
+	    backupDirectory: string;
+	    backupIntervalHours: number;
+	    backupKeep: number;
+	    autoLockMinutes: number;
+	    apiEnabled: boolean;
+	    apiPort: number;
+	    inboxJotId: string;
+	    dailyTemplate: string;
+	    dailyTitlePattern: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Settings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.trashRetentionDays = source["trashRetentionDays"];
+	        this.backupDirectory = source["backupDirectory"];
+	        this.backupIntervalHours = source["backupIntervalHours"];
+	        this.backupKeep = source["backupKeep"];
+	        this.autoLockMinutes = source["autoLockMinutes"];
+	        this.apiEnabled = source["apiEnabled"];
+	        this.apiPort = source["apiPort"];
+	        this.inboxJotId = source["inboxJotId"];
+	        this.dailyTemplate = source["dailyTemplate"];
+	        this.dailyTitlePattern = source["dailyTitlePattern"];
+	    }
+	}
+	
+	export class TagInfo {
+	    name: string;
+	    count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TagInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.count = source["count"];
+	    }
+	}
+
+}
+
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// exportCSS is embedded in exported HTML documents so they look the same
+// wherever they are opened.
+const exportCSS = `body {
+  max-width: 42rem;
+  margin: 2rem auto;
+  padding: 0 1rem;
+  font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, Helvetica, Arial, sans-serif;
+  line-height: 1.6;
+  color: #1f2328;
+}
+article + article {
+  margin-top: 3rem;
+  padding-top: 2rem;
+  border-top: 1px solid #d0d7de;
+}
+h1, h2, h3, h4, h5, h6 { line-height: 1.25; margin: 1.5rem 0 0.75rem; }
+p { margin: 0 0 0.75rem; }
+blockquote {
+  margin: 0 0 0.75rem;
+  padding: 0 1rem;
+  color: #59636e;
+  border-left: 0.25rem solid #d0d7de;
+}
+hr { border: 0; border-top: 1px solid #d0d7de; margin: 1.5rem 0; }
+ul, ol { margin: 0 0 0.75rem; padding-left: 2rem; }
+ul.task-list { list-style: none; padding-left: 0.5rem; }
+ul.task-list input { margin: 0 0.5rem 0 0; }
+ul.task-list li > p { display: inline; }
+a { color: #0969da; }
+.meta { color: #59636e; font-size: 0.875rem; }
+`
+
+// RenderHTML converts a TipTap document to an HTML fragment. linkHTML
+// renders noteLink nodes; if it is nil they become their escaped label.
+func RenderHTML(doc *Node, linkHTML func(link NoteLink) string) string {
+	if doc == nil {
+		return ""
+	}
+	if linkHTML == nil {
+		linkHTML = func(link NoteLink) string { return html.EscapeString(link.Label) }
+	}
+	r := htmlRenderer{linkHTML: linkHTML}
+	var out strings.Builder
+	r.blocks(&out, doc.Content)
+	return out.String()
+}
+
+// htmlRenderer renders blocks to HTML.
+type htmlRenderer struct {
+	linkHTML func(link NoteLink) string
+}
+
+// blocks renders a list of blocks.
+func (r htmlRenderer) blocks(out *strings.Builder, nodes []*Node) {
+	for _, node := range nodes {
+		r.block(out, node)
+	}
+}
+
+// block renders a single block.
+func (r htmlRenderer) block(out *strings.Builder, node *Node) {
+	switch node.Type {
+	case "paragraph":
+		out.WriteString("<p>" + r.inline(node.Content) + "</p>\n")
+	case "heading":
+		level := min(max(node.IntAttr("level", 1), 1), 6)
+		fmt.Fprintf(out, "<h%d>%s</h%d>\n", level, r.inline(node.Content), level)
+	case "blockquote":
+		out.WriteString("<blockquote>\n")
+		r.blocks(out, node.Content)
+		out.WriteString("</blockquote>\n")
+	case "horizontalRule":
+		out.WriteString("<hr>\n")
+	case "bulletList":
+		r.list(out, node, "<ul>")
+	case "orderedList":
+		if start := node.IntAttr("start", 1); start != 1 {
+			r.list(out, node, fmt.Sprintf(`<ol start="%d">`, start))
+		} else {
+			r.list(out, node, "<ol>")
+		}
+	case "taskList":
+		r.list(out, node, `<ul class="task-list">`)
+	default:
+		r.blocks(out, node.Content)
+	}
+}
+
+// list renders the items of a list inside the given opening tag.
+func (r htmlRenderer) list(out *strings.Builder, node *Node, open string) {
+	out.WriteString(open + "\n")
+	for _, item := range node.Content {
+		out.WriteString("<li>")
+		if item.Type == "taskItem" {
+			if item.BoolAttr("checked") {
+				out.WriteString(`<input type="checkbox" disabled checked>`)
+			} else {
+				out.WriteString(`<input type="checkbox" disabled>`)
+			}
+		}
+		r.blocks(out, item.Content)
+		out.WriteString("</li>\n")
+	}
+	out.WriteString("</" + open[1:3] + ">\n")
+}
+
+// inline renders text and inline nodes.
+func (r htmlRenderer) inline(nodes []*Node) string {
+	var text strings.Builder
+	for _, node := range nodes {
+		switch node.Type {
+		case "text":
+			escaped := html.EscapeString(node.Text)
+			for _, mark := range node.Marks {
+				switch mark.Type {
+				case "bold":
+					escaped = "<strong>" + escaped + "</strong>"
+				case "italic":
+					escaped = "<em>" + escaped + "</em>"
+				}
+			}
+			text.WriteString(escaped)
+		case noteLinkType:
+			text.WriteString(r.linkHTML(NoteLink{
+				JotID: node.StringAttr("jotId"),
+				Label: node.StringAttr("label"),
+			}))
+		}
+	}
+	return text.String()
+}
+
+// htmlDocument wraps rendered articles in a standalone HTML document with
+// the export stylesheet.
+func htmlDocument(title, body string) string {
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n" +
+		"<title>" + html.EscapeString(title) + "</title>\n" +
+		"<style>\n" + exportCSS + "</style>\n</head>\n<body>\n" +
+		body + "</body>\n</html>\n"
+}
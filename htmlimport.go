@@ -0,0 +1,409 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ignoredHTMLElements are elements whose content is never imported.
+var ignoredHTMLElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Title:    true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Svg:      true,
+	atom.Math:     true,
+	atom.Canvas:   true,
+	atom.Select:   true,
+	atom.Button:   true,
+	atom.Form:     true,
+}
+
+// blockHTMLElements are elements that start a new block.
+var blockHTMLElements = map[atom.Atom]bool{
+	atom.Address:    true,
+	atom.Article:    true,
+	atom.Aside:      true,
+	atom.Center:     true,
+	atom.Dd:         true,
+	atom.Details:    true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Div:        true,
+	atom.Figcaption: true,
+	atom.Figure:     true,
+	atom.Footer:     true,
+	atom.Header:     true,
+	atom.Main:       true,
+	atom.Nav:        true,
+	atom.P:          true,
+	atom.Section:    true,
+	atom.Summary:    true,
+	atom.Table:      true,
+	atom.Tbody:      true,
+	atom.Td:         true,
+	atom.Tfoot:      true,
+	atom.Th:         true,
+	atom.Thead:      true,
+	atom.Tr:         true,
+}
+
+// HTMLToTipTap converts an HTML fragment to TipTap blocks. Only the
+// structure the editor supports is kept: paragraphs, headings, lists, task
+// lists, blockquotes, rules, and bold and italic text. Links become their
+// text followed by the URL, and everything else, including scripts, styles
+// and embedded media, is dropped, so the result is safe to display. Evernote
+// checkboxes become task items.
+func HTMLToTipTap(root *html.Node) []*Node {
+	c := &htmlConverter{}
+	c.children(root, nil)
+	c.flush()
+	return c.blocks
+}
+
+// parseHTMLFragment parses HTML and returns the node its content is in.
+func parseHTMLFragment(source string) (*html.Node, error) {
+	doc, err := html.Parse(strings.NewReader(source))
+	if err != nil {
+		return nil, err
+	}
+	if body := findHTMLElement(doc, atom.Body); body != nil {
+		return body, nil
+	}
+	return doc, nil
+}
+
+// findHTMLElement returns the first element of the given type below node.
+func findHTMLElement(node *html.Node, element atom.Atom) *html.Node {
+	if node.Type == html.ElementNode && node.DataAtom == element {
+		return node
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := findHTMLElement(child, element); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// htmlAttr returns the value of an attribute of an element.
+func htmlAttr(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// htmlConverter collects the blocks of converted HTML. Inline content is
+// gathered in line until a block boundary ends the paragraph.
+type htmlConverter struct {
+	blocks []*Node
+	line   []*Node
+	// checked is set when the current line starts with a checkbox.
+	checked *bool
+	// pre is set inside preformatted text, which keeps its whitespace.
+	pre bool
+}
+
+// flush ends the current paragraph. A paragraph that started with a
+// checkbox becomes a task item, joining the task list before it.
+func (c *htmlConverter) flush() {
+	line := mergeTextNodes(c.line)
+	if !c.pre {
+		line = trimLine(line)
+	}
+	checked := c.checked
+	c.line, c.checked = nil, nil
+	if len(line) == 0 && checked == nil {
+		return
+	}
+
+	paragraph := &Node{Type: "paragraph", Content: line}
+	if checked == nil {
+		c.blocks = append(c.blocks, paragraph)
+		return
+	}
+	item := &Node{Type: "taskItem", Attrs: map[string]interface{}{"checked": *checked}, Content: []*Node{paragraph}}
+	if last := len(c.blocks) - 1; last >= 0 && c.blocks[last].Type == "taskList" {
+		c.blocks[last].Content = append(c.blocks[last].Content, item)
+		return
+	}
+	c.blocks = append(c.blocks, &Node{Type: "taskList", Content: []*Node{item}})
+}
+
+// text adds text to the current line. Outside preformatted text runs of
+// whitespace collapse to a single space.
+func (c *htmlConverter) text(text string, marks []Mark) {
+	if c.pre {
+		for i, line := range strings.Split(text, "\n") {
+			if i > 0 {
+				c.flush()
+			}
+			c.line = appendText(c.line, line, marks)
+		}
+		return
+	}
+	collapsed := strings.Join(strings.Fields(text), " ")
+	if collapsed == "" {
+		collapsed = " "
+	} else {
+		if strings.TrimLeft(text, " \t\r\n") != text {
+			collapsed = " " + collapsed
+		}
+		if strings.TrimRight(text, " \t\r\n") != text {
+			collapsed += " "
+		}
+	}
+	if last := len(c.line) - 1; last >= 0 && c.line[last].Type == "text" && strings.HasSuffix(c.line[last].Text, " ") {
+		collapsed = strings.TrimPrefix(collapsed, " ")
+	}
+	c.line = appendText(c.line, collapsed, marks)
+}
+
+// children converts the children of node.
+func (c *htmlConverter) children(node *html.Node, marks []Mark) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		c.node(child, marks)
+	}
+}
+
+// nested converts the children of node into blocks of their own.
+func (c *htmlConverter) nested(node *html.Node, marks []Mark) []*Node {
+	inner := &htmlConverter{pre: c.pre}
+	inner.children(node, marks)
+	inner.flush()
+	return inner.blocks
+}
+
+// node converts a single node.
+func (c *htmlConverter) node(node *html.Node, marks []Mark) {
+	switch node.Type {
+	case html.TextNode:
+		c.text(node.Data, marks)
+		return
+	case html.ElementNode:
+	default:
+		c.children(node, marks)
+		return
+	}
+
+	switch {
+	case ignoredHTMLElements[node.DataAtom]:
+		return
+	case blockHTMLElements[node.DataAtom]:
+		c.flush()
+		c.children(node, marks)
+		c.flush()
+		return
+	}
+
+	switch node.DataAtom {
+	case atom.Br:
+		c.flush()
+	case atom.B, atom.Strong:
+		c.children(node, withMark(marks, "bold"))
+	case atom.I, atom.Em, atom.Cite:
+		c.children(node, withMark(marks, "italic"))
+	case atom.A:
+		before := len(c.line)
+		c.children(node, marks)
+		href := strings.TrimSpace(htmlAttr(node, "href"))
+		label := strings.TrimSpace(inlineText(&Node{Content: c.line[before:]}, noteLinkLabel))
+		if (strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://")) && href != label {
+			if label == "" {
+				c.text(href, marks)
+			} else {
+				c.text(" ("+href+")", marks)
+			}
+		}
+	case atom.Img:
+		if alt := strings.TrimSpace(htmlAttr(node, "alt")); alt != "" {
+			c.text(alt, marks)
+		}
+	case atom.Input:
+		if strings.EqualFold(htmlAttr(node, "type"), "checkbox") {
+			checked := hasHTMLAttr(node, "checked")
+			c.checked = &checked
+		}
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		c.flush()
+		var content []*Node
+		for _, block := range c.nested(node, marks) {
+			if len(content) > 0 {
+				content = append(content, &Node{Type: "text", Text: " "})
+			}
+			content = append(content, blockInline(block)...)
+		}
+		if len(content) > 0 {
+			level := int(node.Data[1] - '0')
+			c.blocks = append(c.blocks, &Node{Type: "heading", Attrs: map[string]interface{}{"level": level}, Content: mergeTextNodes(content)})
+		}
+	case atom.Blockquote:
+		c.flush()
+		if content := c.nested(node, marks); len(content) > 0 {
+			c.blocks = append(c.blocks, &Node{Type: "blockquote", Content: withParagraph(content)})
+		}
+	case atom.Hr:
+		c.flush()
+		c.blocks = append(c.blocks, &Node{Type: "horizontalRule"})
+	case atom.Pre:
+		c.flush()
+		pre := c.pre
+		c.pre = true
+		c.children(node, marks)
+		c.flush()
+		c.pre = pre
+	case atom.Ul, atom.Ol:
+		c.flush()
+		if list := c.list(node, marks); list != nil {
+			c.blocks = append(c.blocks, list)
+		}
+	case atom.Li:
+		// A list item outside of a list is a paragraph.
+		c.flush()
+		c.children(node, marks)
+		c.flush()
+	default:
+		if node.Data == "en-todo" {
+			checked := htmlAttr(node, "checked") == "true"
+			c.flush()
+			c.checked = &checked
+		}
+		c.children(node, marks)
+	}
+}
+
+// list converts a ul or ol element. Evernote marks checklists with styles
+// on the list and its items; a list whose items start with checkboxes is a
+// task list too.
+func (c *htmlConverter) list(node *html.Node, marks []Mark) *Node {
+	isTask := strings.Contains(htmlAttr(node, "style"), "--en-todo:true")
+	var items []*Node
+	var checks []*bool
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if child.DataAtom != atom.Li {
+			// Nested lists directly inside a list belong to the item before.
+			inner := &htmlConverter{pre: c.pre}
+			inner.node(child, marks)
+			inner.flush()
+			content := inner.blocks
+			if len(items) > 0 {
+				items[len(items)-1].Content = append(items[len(items)-1].Content, content...)
+			}
+			continue
+		}
+
+		var checked *bool
+		if isTask {
+			value := strings.Contains(htmlAttr(child, "style"), "--en-checked:true")
+			checked = &value
+		}
+		content := c.nested(child, marks)
+		// A checkbox at the start of the item comes back as a task list.
+		if len(content) > 0 && content[0].Type == "taskList" && len(content[0].Content) == 1 {
+			value := content[0].Content[0].BoolAttr("checked")
+			checked = &value
+			content = append(content[0].Content[0].Content, content[1:]...)
+		}
+		items = append(items, &Node{Type: "listItem", Content: withParagraph(content)})
+		checks = append(checks, checked)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	for _, checked := range checks {
+		if checked != nil {
+			isTask = true
+		}
+	}
+	list := &Node{Type: "bulletList", Content: items}
+	switch {
+	case isTask:
+		list.Type = "taskList"
+		for i, item := range items {
+			item.Type = "taskItem"
+			item.Attrs = map[string]interface{}{"checked": checks[i] != nil && *checks[i]}
+		}
+	case node.DataAtom == atom.Ol:
+		start := 1
+		if value := htmlAttr(node, "start"); value != "" {
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				start = n
+			}
+		}
+		list.Type = "orderedList"
+		list.Attrs = map[string]interface{}{"start": start}
+	}
+	return list
+}
+
+// hasHTMLAttr reports whether an element has an attribute.
+func hasHTMLAttr(node *html.Node, name string) bool {
+	for _, attr := range node.Attr {
+		if attr.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// withMark returns marks with one more mark added.
+func withMark(marks []Mark, mark string) []Mark {
+	for _, existing := range marks {
+		if existing.Type == mark {
+			return marks
+		}
+	}
+	return append(append([]Mark{}, marks...), Mark{Type: mark})
+}
+
+// blockInline returns the inline content of a block and the blocks in it.
+func blockInline(block *Node) []*Node {
+	if block.Type == "paragraph" || block.Type == "heading" {
+		return block.Content
+	}
+	var content []*Node
+	for _, child := range block.Content {
+		if inline := blockInline(child); len(inline) > 0 {
+			if len(content) > 0 {
+				content = append(content, &Node{Type: "text", Text: " "})
+			}
+			content = append(content, inline...)
+		}
+	}
+	return content
+}
+
+// trimLine removes the whitespace at the start and end of a line.
+func trimLine(line []*Node) []*Node {
+	for len(line) > 0 && line[0].Type == "text" {
+		line[0].Text = strings.TrimLeft(line[0].Text, " ")
+		if line[0].Text != "" {
+			break
+		}
+		line = line[1:]
+	}
+	for len(line) > 0 && line[len(line)-1].Type == "text" {
+		last := line[len(line)-1]
+		last.Text = strings.TrimRight(last.Text, " ")
+		if last.Text != "" {
+			break
+		}
+		line = line[:len(line)-1]
+	}
+	return line
+}
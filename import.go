@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// importBatchSize is the number of jots a streaming import adds to the
+// store at a time.
+const importBatchSize = 500
+
+// ImportReport summarizes an import.
+type ImportReport struct {
+	// DryRun is set when nothing was stored; the counts are what would have
+	// been imported.
+	DryRun   bool `json:"dryRun"`
+	Imported int  `json:"imported"`
+	// Updated counts existing jots replaced by newer copies.
+	Updated int `json:"updated,omitempty"`
+	Folders int `json:"folders"`
+	// IDs are the ids of the imported jots.
+	IDs     []string      `json:"ids"`
+	Skipped []SkippedFile `json:"skipped"`
+	// Repaired lists the jots whose content did not follow the editor
+	// schema, with what was fixed.
+	Repaired []SkippedFile `json:"repaired"`
+	// UnresolvedLinks are links to notes that were not part of the import.
+	UnresolvedLinks []string `json:"unresolvedLinks"`
+}
+
+// SkippedFile is a file an import could not use.
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// newImportReport returns an empty report.
+func newImportReport(dryRun bool) *ImportReport {
+	return &ImportReport{DryRun: dryRun, IDs: []string{}, Skipped: []SkippedFile{}, Repaired: []SkippedFile{}, UnresolvedLinks: []string{}}
+}
+
+// skip records a file the import could not use.
+func (r *ImportReport) skip(path, reason string, args ...interface{}) {
+	r.Skipped = append(r.Skipped, SkippedFile{Path: path, Reason: fmt.Sprintf(reason, args...)})
+}
+
+// repair makes the content of an imported jot follow the editor schema and
+// records what was fixed.
+func (r *ImportReport) repair(name string, jot *Jot) {
+	repaired, problems := RepairDocument(jot.Content)
+	if len(problems) == 0 {
+		return
+	}
+	jot.Content = repaired
+	r.Repaired = append(r.Repaired, SkippedFile{Path: name, Reason: describeProblems(problems)})
+}
+
+// Import adds new jots and folders to the store in one write. Folders may
+// refer to each other or to existing folders as parents; a top-level
+// imported folder whose name is taken is renamed. Imported jots must have
+// ids that are not in use.
+func (s *Store) Import(jots []*Jot, folders []*Folder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change := storeChange{
+		jots:    make(map[string]*Jot, len(jots)),
+		folders: make(map[string]*Folder, len(folders)),
+	}
+	for _, folder := range folders {
+		if _, ok := s.folders[folder.ID]; ok {
+			return fmt.Errorf("folder %s already exists", folder.ID)
+		}
+		change.folders[folder.ID] = folder
+	}
+	for _, folder := range folders {
+		if _, imported := change.folders[folder.ParentID]; imported {
+			continue
+		}
+		if _, ok := s.folders[folder.ParentID]; folder.ParentID != "" && !ok {
+			return fmt.Errorf("folder %s not found", folder.ParentID)
+		}
+		folder.Name = s.uniqueFolderNameLocked(folder.ParentID, folder.ID, folder.Name)
+		folder.Position = s.nextFolderPositionLocked(folder.ParentID)
+	}
+
+	now := time.Now()
+	for _, jot := range jots {
+		if _, ok := s.jots[jot.ID]; ok {
+			return fmt.Errorf("jot %s already exists", jot.ID)
+		}
+		if _, ok := s.trash[jot.ID]; ok {
+			return fmt.Errorf("jot %s already exists in the trash", jot.ID)
+		}
+		_, imported := change.folders[jot.FolderID]
+		if _, ok := s.folders[jot.FolderID]; !imported && !ok {
+			jot.FolderID = ""
+		}
+		if jot.Content == nil {
+			jot.Content = NewDocument()
+		}
+		if jot.CreatedAt.IsZero() {
+			jot.CreatedAt = now
+		}
+		if jot.UpdatedAt.IsZero() {
+			jot.UpdatedAt = jot.CreatedAt
+		}
+		jot.Lock = nil
+		change.jots[jot.ID] = jot
+	}
+
+	return s.writeLocked(change)
+}
+
+// newImportFolder returns a new top-level folder for imported jots.
+func newImportFolder(name string) *Folder {
+	name, err := validateFolderName(name)
+	if err != nil {
+		name = "Imported notes"
+	}
+	return &Folder{ID: uuid.NewString(), Name: name, SortOrder: SortByUpdated, CreatedAt: time.Now()}
+}
+
+// importBatcher adds the jots of a streaming import to the store in batches,
+// so the import does not hold every jot in memory. The folders are added
+// with the first batch, so an import that finds no jots creates none. In a
+// dry run only the report is updated.
+type importBatcher struct {
+	store   *Store
+	report  *ImportReport
+	folders []*Folder
+	jots    []*Jot
+}
+
+// add queues a jot, writing the batch when it is full.
+func (b *importBatcher) add(jot *Jot) error {
+	b.report.repair(jot.Title, jot)
+	if b.report.DryRun {
+		b.report.Imported++
+		b.report.Folders += len(b.folders)
+		b.folders = nil
+		return nil
+	}
+	b.jots = append(b.jots, jot)
+	if len(b.jots) >= importBatchSize {
+		return b.flush()
+	}
+	return nil
+}
+
+// flush writes the queued jots.
+func (b *importBatcher) flush() error {
+	if len(b.jots) == 0 {
+		return nil
+	}
+	if err := b.store.Import(b.jots, b.folders); err != nil {
+		return err
+	}
+	b.report.Imported += len(b.jots)
+	b.report.Folders += len(b.folders)
+	for _, jot := range b.jots {
+		b.report.IDs = append(b.report.IDs, jot.ID)
+	}
+	b.folders, b.jots = nil, nil
+	return nil
+}
+
+// tagFrontMatter returns the front matter listing tags, for importers whose
+// notes have tags outside their text. Tags that are not valid tag names
+// after replacing spaces with dashes are left out.
+func tagFrontMatter(labels []string) []*Node {
+	var tags []string
+	for _, label := range labels {
+		if tag, ok := NormalizeTag(strings.Join(strings.Fields(label), "-")); ok {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return frontMatterNodes([]string{"tags: [" + strings.Join(tags, ", ") + "]"})
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// enexTimeLayout is the format of timestamps in ENEX files.
+const enexTimeLayout = "20060102T150405Z"
+
+// untitledJotTitle is the title of an imported note without one, matching
+// new jots in the editor.
+const untitledJotTitle = "Untitled Jot"
+
+// enexNote is a note read from an ENEX file. Attachments are counted but
+// not kept, since the editor cannot show them.
+type enexNote struct {
+	Title       string
+	Content     string
+	Created     string
+	Updated     string
+	Tags        []string
+	Attachments int
+}
+
+// ImportENEX imports an Evernote export into a new folder named after the
+// file. The file is read one note at a time and the jots are stored in
+// batches, so exports of any size can be imported. With dryRun set nothing
+// is stored and the report says what would be imported. Notes that cannot
+// be converted and attachments, which are not imported, are listed in the
+// report.
+func ImportENEX(store *Store, path string, dryRun bool) (*ImportReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening ENEX file: %w", err)
+	}
+	defer file.Close()
+
+	name := filepath.Base(path)
+	report := newImportReport(dryRun)
+	folder := newImportFolder(strings.TrimSuffix(name, filepath.Ext(name)))
+	batch := &importBatcher{store: store, report: report, folders: []*Folder{folder}}
+
+	decoder := xml.NewDecoder(bufio.NewReader(file))
+	decoder.Entity = xml.HTMLEntity
+	index := 0
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// Keep what was read before the damage.
+			report.skip(fmt.Sprintf("%s, after note %d", name, index), "%v", err)
+			break
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+
+		index++
+		note, err := decodeENEXNote(decoder)
+		if err != nil {
+			report.skip(fmt.Sprintf("%s, note %d", name, index), "%v", err)
+			break
+		}
+		label := fmt.Sprintf("%s, note %d %q", name, index, note.Title)
+		jot, err := note.jot(folder.ID)
+		if err != nil {
+			report.skip(label, "%v", err)
+			continue
+		}
+		if note.Attachments > 0 {
+			report.skip(label, "attachments not imported: %d", note.Attachments)
+		}
+		if err := batch.add(jot); err != nil {
+			return nil, err
+		}
+	}
+	if err := batch.flush(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// decodeENEXNote reads the elements of a note up to its end tag. Attachment
+// data is skipped without being held in memory.
+func decodeENEXNote(decoder *xml.Decoder) (*enexNote, error) {
+	note := &enexNote{}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch element := token.(type) {
+		case xml.EndElement:
+			return note, nil
+		case xml.StartElement:
+			var target *string
+			switch element.Name.Local {
+			case "title":
+				target = &note.Title
+			case "content":
+				target = &note.Content
+			case "created":
+				target = &note.Created
+			case "updated":
+				target = &note.Updated
+			case "tag":
+				var tag string
+				if err := decoder.DecodeElement(&tag, &element); err != nil {
+					return nil, err
+				}
+				note.Tags = append(note.Tags, tag)
+				continue
+			case "resource":
+				note.Attachments++
+			}
+			if target == nil {
+				if err := decoder.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := decoder.DecodeElement(target, &element); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// jot converts the note to a jot in the given folder.
+func (n *enexNote) jot(folderID string) (*Jot, error) {
+	root, err := parseHTMLFragment(n.Content)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing note content: %w", err)
+	}
+	doc := NewDocument()
+	doc.Content = append(tagFrontMatter(n.Tags), HTMLToTipTap(root)...)
+
+	title := strings.TrimSpace(n.Title)
+	if title == "" {
+		title = untitledJotTitle
+	}
+	return &Jot{
+		ID:        uuid.NewString(),
+		Title:     title,
+		Content:   doc,
+		CreatedAt: parseENEXTime(n.Created),
+		UpdatedAt: parseENEXTime(n.Updated),
+		FolderID:  folderID,
+	}, nil
+}
+
+// parseENEXTime parses an ENEX timestamp, returning the zero time for a
+// missing or invalid one.
+func parseENEXTime(value string) time.Time {
+	parsed, err := time.Parse(enexTimeLayout, strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// keepNote is a note in a Google Keep Takeout export.
+type keepNote struct {
+	Title       string `json:"title"`
+	TextContent string `json:"textContent"`
+	ListContent []struct {
+		Text      string `json:"text"`
+		IsChecked bool   `json:"isChecked"`
+	} `json:"listContent"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Attachments             []json.RawMessage `json:"attachments"`
+	IsTrashed               bool              `json:"isTrashed"`
+	CreatedTimestampUsec    int64             `json:"createdTimestampUsec"`
+	UserEditedTimestampUsec int64             `json:"userEditedTimestampUsec"`
+}
+
+// maxKeepTitleLength is the length a title taken from the text of an
+// untitled Keep note is cut to.
+const maxKeepTitleLength = 60
+
+// ImportKeep imports a Google Keep Takeout export, either the zip file or
+// the extracted folder, into a new "Google Keep" folder. Checklists become
+// task lists and labels become tags. Notes in the Keep trash are skipped.
+// With dryRun set nothing is stored and the report says what would be
+// imported.
+func ImportKeep(store *Store, source string, dryRun bool) (*ImportReport, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Keep export: %w", err)
+	}
+
+	report := newImportReport(dryRun)
+	folder := newImportFolder("Google Keep")
+	batch := &importBatcher{store: store, report: report, folders: []*Folder{folder}}
+	importFile := func(name string, open func() (io.ReadCloser, error)) error {
+		switch strings.ToLower(path.Ext(name)) {
+		case ".json":
+		case ".html":
+			// Takeout has an HTML copy of every note next to its JSON.
+			return nil
+		default:
+			report.skip(name, "not a Keep note")
+			return nil
+		}
+
+		reader, err := open()
+		if err != nil {
+			report.skip(name, "%v", err)
+			return nil
+		}
+		defer reader.Close()
+		var note keepNote
+		if err := json.NewDecoder(io.LimitReader(reader, maxImportFileSize)).Decode(&note); err != nil {
+			report.skip(name, "not a Keep note: %v", err)
+			return nil
+		}
+		if note.Title == "" && note.TextContent == "" && len(note.ListContent) == 0 && len(note.Attachments) == 0 {
+			report.skip(name, "not a Keep note")
+			return nil
+		}
+		if note.IsTrashed {
+			report.skip(name, "in the Keep trash")
+			return nil
+		}
+		if len(note.Attachments) > 0 {
+			report.skip(name, "attachments not imported: %d", len(note.Attachments))
+		}
+		return batch.add(note.jot(folder.ID))
+	}
+
+	if info.IsDir() {
+		err = filepath.WalkDir(source, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			rel, _ := filepath.Rel(source, filePath)
+			return importFile(filepath.ToSlash(rel), func() (io.ReadCloser, error) {
+				return os.Open(filePath)
+			})
+		})
+	} else {
+		err = importKeepZip(source, importFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := batch.flush(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// importKeepZip calls importFile for each file in a Takeout zip.
+func importKeepZip(path string, importFile func(name string, open func() (io.ReadCloser, error)) error) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("error opening Keep export: %w", err)
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if err := importFile(file.Name, file.Open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jot converts the note to a jot in the given folder. An untitled note is
+// titled with the start of its text.
+func (n *keepNote) jot(folderID string) *Jot {
+	labels := make([]string, len(n.Labels))
+	for i, label := range n.Labels {
+		labels[i] = label.Name
+	}
+	doc := NewDocument()
+	doc.Content = tagFrontMatter(labels)
+	var lines []string
+	if n.TextContent != "" {
+		lines = strings.Split(strings.ReplaceAll(n.TextContent, "\r\n", "\n"), "\n")
+	}
+	for _, line := range lines {
+		doc.Content = append(doc.Content, textParagraph(line))
+	}
+	if len(n.ListContent) > 0 {
+		list := &Node{Type: "taskList"}
+		for _, item := range n.ListContent {
+			list.Content = append(list.Content, &Node{
+				Type:    "taskItem",
+				Attrs:   map[string]interface{}{"checked": item.IsChecked},
+				Content: []*Node{textParagraph(item.Text)},
+			})
+		}
+		doc.Content = append(doc.Content, list)
+	}
+
+	title := strings.TrimSpace(n.Title)
+	for _, item := range n.ListContent {
+		lines = append(lines, item.Text)
+	}
+	for _, line := range lines {
+		if title != "" {
+			break
+		}
+		title = strings.TrimSpace(line)
+	}
+	if runes := []rune(title); len(runes) > maxKeepTitleLength {
+		title = strings.TrimSpace(string(runes[:maxKeepTitleLength])) + "…"
+	}
+	if title == "" {
+		title = untitledJotTitle
+	}
+
+	return &Jot{
+		ID:        uuid.NewString(),
+		Title:     title,
+		Content:   doc,
+		CreatedAt: keepTime(n.CreatedTimestampUsec),
+		UpdatedAt: keepTime(n.UserEditedTimestampUsec),
+		FolderID:  folderID,
+	}
+}
+
+// keepTime converts a Keep timestamp in microseconds, returning the zero
+// time for a missing one.
+func keepTime(usec int64) time.Time {
+	if usec <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(usec)
+}
@@ -0,0 +1,567 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// maxImportFileSize is the largest Markdown file an import reads.
+const maxImportFileSize = 10 << 20
+
+// wikiLinkPattern matches [[target]], [[target|alias]] and ![[embed]].
+var wikiLinkPattern = regexp.MustCompile(`(!?)\[\[([^\[\]\n]+)\]\]`)
+
+// markdownParser parses CommonMark with task list items.
+var markdownParser = goldmark.New(goldmark.WithExtensions(extension.TaskList))
+
+// markdownNote is a Markdown file found by an import.
+type markdownNote struct {
+	path     string
+	id       string
+	title    string
+	folderID string
+	info     fs.FileInfo
+	front    []string
+	body     []byte
+}
+
+// ImportMarkdownFolder imports every Markdown file below root, such as an
+// Obsidian vault, into a new folder named after root. Subdirectories become
+// folders. Front matter is kept at the top of the jot, where tags are read
+// from, and a "title" in it replaces the file name as the title. Wikilinks
+// and relative Markdown links to imported files become note links. Files
+// that are not Markdown or cannot be read are listed in the report; hidden
+// files and directories such as .obsidian are ignored.
+func ImportMarkdownFolder(store *Store, root string) (*ImportReport, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving import folder: %w", err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("error reading import folder: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a folder", root)
+	}
+
+	report := newImportReport(false)
+	top := newImportFolder(filepath.Base(root))
+	folders := map[string]*Folder{".": top}
+	positions := make(map[string]int)
+	var folderFor func(dir string) *Folder
+	folderFor = func(dir string) *Folder {
+		if folder, ok := folders[dir]; ok {
+			return folder
+		}
+		parent := folderFor(path.Dir(dir))
+		folder := &Folder{
+			ID:        uuid.NewString(),
+			Name:      path.Base(dir),
+			ParentID:  parent.ID,
+			SortOrder: SortByUpdated,
+			Position:  positions[parent.ID],
+			CreatedAt: top.CreatedAt,
+		}
+		positions[parent.ID]++
+		folders[dir] = folder
+		return folder
+	}
+
+	var notes []*markdownNote
+	err = filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		rel, _ := filepath.Rel(root, filePath)
+		rel = filepath.ToSlash(rel)
+		if err != nil {
+			report.skip(rel, "%v", err)
+			if entry != nil && entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if filePath == root {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if ext != ".md" && ext != ".markdown" {
+			report.skip(rel, "not a Markdown file")
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			report.skip(rel, "%v", err)
+			return nil
+		}
+		if info.Size() > maxImportFileSize {
+			report.skip(rel, "larger than %d MB", maxImportFileSize>>20)
+			return nil
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			report.skip(rel, "%v", err)
+			return nil
+		}
+		if !utf8.Valid(data) {
+			report.skip(rel, "not valid UTF-8 text")
+			return nil
+		}
+
+		note := &markdownNote{
+			path:     rel,
+			id:       uuid.NewString(),
+			title:    strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())),
+			folderID: folderFor(path.Dir(rel)).ID,
+			info:     info,
+		}
+		note.front, note.body = splitFrontMatter(data)
+		if title := frontMatterValue(note.front, "title"); title != "" {
+			note.title = title
+		}
+		notes = append(notes, note)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading import folder: %w", err)
+	}
+
+	// Links resolve by path relative to the root first, then by file name,
+	// the way Obsidian resolves them.
+	byPath := make(map[string]*markdownNote, len(notes))
+	byName := make(map[string]*markdownNote, len(notes))
+	for _, note := range notes {
+		key := strings.ToLower(strings.TrimSuffix(note.path, path.Ext(note.path)))
+		byPath[key] = note
+		if _, ok := byName[path.Base(key)]; !ok {
+			byName[path.Base(key)] = note
+		}
+	}
+	resolve := func(target string) (*markdownNote, bool) {
+		key := strings.ToLower(strings.TrimSpace(target))
+		key = strings.TrimSuffix(strings.TrimSuffix(key, ".md"), ".markdown")
+		if note, ok := byPath[strings.TrimPrefix(key, "/")]; ok {
+			return note, true
+		}
+		note, ok := byName[path.Base(key)]
+		return note, ok
+	}
+
+	unresolved := make(map[string]struct{})
+	jots := make([]*Jot, 0, len(notes))
+	for _, note := range notes {
+		converter := &markdownConverter{
+			source:     note.body,
+			dir:        path.Dir(note.path),
+			resolve:    resolve,
+			unresolved: unresolved,
+		}
+		doc := NewDocument()
+		doc.Content = append(frontMatterNodes(note.front), converter.convert()...)
+		jot := &Jot{
+			ID:        note.id,
+			Title:     note.title,
+			Content:   doc,
+			CreatedAt: fileCreatedAt(note.info),
+			UpdatedAt: note.info.ModTime(),
+			FolderID:  note.folderID,
+		}
+		report.repair(note.path, jot)
+		jots = append(jots, jot)
+	}
+	for target := range unresolved {
+		report.UnresolvedLinks = append(report.UnresolvedLinks, target)
+	}
+	sort.Strings(report.UnresolvedLinks)
+
+	if len(jots) == 0 {
+		return report, nil
+	}
+	folderList := make([]*Folder, 0, len(folders))
+	for _, folder := range folders {
+		folderList = append(folderList, folder)
+	}
+	if err := store.Import(jots, folderList); err != nil {
+		return nil, err
+	}
+	report.Imported = len(jots)
+	report.Folders = len(folderList)
+	for _, jot := range jots {
+		report.IDs = append(report.IDs, jot.ID)
+	}
+	return report, nil
+}
+
+// MarkdownToDocument converts Markdown to a TipTap document. Front matter
+// is kept at the top, and wikilinks stay text since there is nothing to
+// resolve them against.
+func MarkdownToDocument(source []byte) *Node {
+	front, body := splitFrontMatter(source)
+	converter := &markdownConverter{
+		source: body,
+		dir:    ".",
+		resolve: func(string) (*markdownNote, bool) {
+			return nil, false
+		},
+		unresolved: make(map[string]struct{}),
+	}
+	doc := NewDocument()
+	doc.Content = append(frontMatterNodes(front), converter.convert()...)
+	return doc
+}
+
+// splitFrontMatter splits YAML front matter between "---" lines off the
+// start of a Markdown file.
+func splitFrontMatter(data []byte) ([]string, []byte) {
+	content := strings.TrimPrefix(string(data), "\uFEFF")
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != frontMatterDelimiter {
+		return nil, []byte(content)
+	}
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == frontMatterDelimiter || line == "..." {
+			front := make([]string, 0, i-1)
+			for _, frontLine := range lines[1:i] {
+				front = append(front, strings.TrimRight(frontLine, "\r\n"))
+			}
+			return front, []byte(strings.Join(lines[i+1:], ""))
+		}
+	}
+	return nil, []byte(content)
+}
+
+// frontMatterValue returns the value of a "key: value" front-matter line.
+func frontMatterValue(front []string, key string) string {
+	for _, line := range front {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), key) {
+			return strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return ""
+}
+
+// frontMatterNodes turns front-matter lines into the "---" delimited
+// paragraphs that tags are read from.
+func frontMatterNodes(front []string) []*Node {
+	if len(front) == 0 {
+		return nil
+	}
+	nodes := []*Node{textParagraph(frontMatterDelimiter)}
+	for _, line := range front {
+		if strings.TrimSpace(line) != "" {
+			nodes = append(nodes, textParagraph(line))
+		}
+	}
+	return append(nodes, textParagraph(frontMatterDelimiter))
+}
+
+// textParagraph returns a paragraph holding plain text.
+func textParagraph(text string) *Node {
+	paragraph := &Node{Type: "paragraph"}
+	if text != "" {
+		paragraph.Content = []*Node{{Type: "text", Text: text}}
+	}
+	return paragraph
+}
+
+// markdownConverter converts a parsed Markdown file to TipTap nodes.
+type markdownConverter struct {
+	source []byte
+	// dir is the directory of the file, for resolving relative links.
+	dir        string
+	resolve    func(target string) (*markdownNote, bool)
+	unresolved map[string]struct{}
+}
+
+// convert parses the source and returns the top-level TipTap blocks.
+func (c *markdownConverter) convert() []*Node {
+	doc := markdownParser.Parser().Parse(text.NewReader(c.source))
+	return c.blocks(doc)
+}
+
+// blocks converts the block children of node.
+func (c *markdownConverter) blocks(node ast.Node) []*Node {
+	var nodes []*Node
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		nodes = append(nodes, c.block(child)...)
+	}
+	return nodes
+}
+
+// block converts a single block. TipTap has no code blocks or line breaks
+// here, so code becomes a paragraph per line, as do hard line breaks.
+func (c *markdownConverter) block(node ast.Node) []*Node {
+	switch n := node.(type) {
+	case *ast.Paragraph, *ast.TextBlock:
+		var paragraphs []*Node
+		for _, line := range c.inlineLines(node) {
+			paragraphs = append(paragraphs, &Node{Type: "paragraph", Content: line})
+		}
+		return paragraphs
+	case *ast.Heading:
+		var content []*Node
+		for i, line := range c.inlineLines(node) {
+			if i > 0 {
+				content = append(content, &Node{Type: "text", Text: " "})
+			}
+			content = append(content, line...)
+		}
+		return []*Node{{Type: "heading", Attrs: map[string]interface{}{"level": n.Level}, Content: content}}
+	case *ast.Blockquote:
+		return []*Node{{Type: "blockquote", Content: withParagraph(c.blocks(node))}}
+	case *ast.ThematicBreak:
+		return []*Node{{Type: "horizontalRule"}}
+	case *ast.List:
+		return []*Node{c.list(n)}
+	case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock:
+		var paragraphs []*Node
+		lines := node.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			segment := lines.At(i)
+			paragraphs = append(paragraphs, textParagraph(strings.TrimRight(string(segment.Value(c.source)), "\r\n")))
+		}
+		return paragraphs
+	default:
+		return c.blocks(node)
+	}
+}
+
+// list converts a list. A list with any checkbox item becomes a task list.
+func (c *markdownConverter) list(list *ast.List) *Node {
+	isTask := false
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		if taskCheckBox(item) != nil {
+			isTask = true
+		}
+	}
+
+	node := &Node{Type: "bulletList"}
+	itemType := "listItem"
+	switch {
+	case isTask:
+		node.Type, itemType = "taskList", "taskItem"
+	case list.IsOrdered():
+		node.Type = "orderedList"
+		node.Attrs = map[string]interface{}{"start": list.Start}
+	}
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		converted := &Node{Type: itemType, Content: withParagraph(c.blocks(item))}
+		if isTask {
+			box := taskCheckBox(item)
+			converted.Attrs = map[string]interface{}{"checked": box != nil && box.IsChecked}
+		}
+		node.Content = append(node.Content, converted)
+	}
+	return node
+}
+
+// taskCheckBox returns the checkbox at the start of a list item, if any.
+func taskCheckBox(item ast.Node) *extast.TaskCheckBox {
+	first := item.FirstChild()
+	if first == nil {
+		return nil
+	}
+	box, _ := first.FirstChild().(*extast.TaskCheckBox)
+	return box
+}
+
+// withParagraph makes sure content starts with a paragraph, as list items
+// and blockquotes require.
+func withParagraph(content []*Node) []*Node {
+	if len(content) == 0 || content[0].Type != "paragraph" {
+		return append([]*Node{{Type: "paragraph"}}, content...)
+	}
+	return content
+}
+
+// inlineLines converts the inline children of a block, split at hard line
+// breaks.
+func (c *markdownConverter) inlineLines(block ast.Node) [][]*Node {
+	lines := [][]*Node{nil}
+	add := func(text string, marks []Mark) {
+		if text != "" {
+			lines[len(lines)-1] = append(lines[len(lines)-1], &Node{Type: "text", Text: text, Marks: marks})
+		}
+	}
+
+	var walk func(node ast.Node, marks []Mark)
+	walkChildren := func(node ast.Node, marks []Mark) {
+		for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+			walk(child, marks)
+		}
+	}
+	walk = func(node ast.Node, marks []Mark) {
+		switch n := node.(type) {
+		case *ast.Text:
+			add(string(n.Segment.Value(c.source)), marks)
+			if n.HardLineBreak() {
+				lines = append(lines, nil)
+			} else if n.SoftLineBreak() {
+				add(" ", marks)
+			}
+		case *ast.String:
+			add(string(n.Value), marks)
+		case *ast.CodeSpan:
+			add(string(n.Text(c.source)), marks)
+		case *ast.Emphasis:
+			mark := Mark{Type: "italic"}
+			if n.Level >= 2 {
+				mark.Type = "bold"
+			}
+			walkChildren(node, append(append([]Mark{}, marks...), mark))
+		case *ast.Link:
+			destination := string(n.Destination)
+			if note, ok := c.resolveRelative(destination); ok {
+				lines[len(lines)-1] = append(lines[len(lines)-1], noteLinkNode(note))
+				return
+			}
+			walkChildren(node, marks)
+			if destination != "" && destination != string(n.Text(c.source)) {
+				add(" ("+destination+")", marks)
+			}
+		case *ast.AutoLink:
+			add(string(n.URL(c.source)), marks)
+		case *ast.RawHTML:
+			for i := 0; i < n.Segments.Len(); i++ {
+				segment := n.Segments.At(i)
+				add(string(segment.Value(c.source)), marks)
+			}
+		case *extast.TaskCheckBox:
+		default:
+			walkChildren(node, marks)
+		}
+	}
+	walk(block, nil)
+
+	for i, line := range lines {
+		lines[i] = c.wikiLinks(mergeTextNodes(line))
+	}
+	return lines
+}
+
+// resolveRelative resolves a Markdown link to another imported file.
+func (c *markdownConverter) resolveRelative(destination string) (*markdownNote, bool) {
+	if destination == "" || strings.Contains(destination, "://") || strings.HasPrefix(destination, "mailto:") {
+		return nil, false
+	}
+	if unescaped, err := url.PathUnescape(destination); err == nil {
+		destination = unescaped
+	}
+	destination, _, _ = strings.Cut(destination, "#")
+	ext := strings.ToLower(path.Ext(destination))
+	if ext != ".md" && ext != ".markdown" {
+		return nil, false
+	}
+	return c.resolve(path.Join(c.dir, destination))
+}
+
+// wikiLinks splits the [[wikilinks]] out of text nodes and turns those that
+// point at imported files into note links. Others are kept as text and
+// recorded as unresolved, except embeds of attachments.
+func (c *markdownConverter) wikiLinks(nodes []*Node) []*Node {
+	var result []*Node
+	for _, node := range nodes {
+		if node.Type != "text" {
+			result = append(result, node)
+			continue
+		}
+		rest := node.Text
+		for {
+			match := wikiLinkPattern.FindStringSubmatchIndex(rest)
+			if match == nil {
+				break
+			}
+			embed := match[3] > match[2]
+			target, _, _ := strings.Cut(rest[match[4]:match[5]], "|")
+			target, _, _ = strings.Cut(target, "#")
+			target, _, _ = strings.Cut(target, "^")
+			note, ok := c.resolve(target)
+			if !ok {
+				if ext := path.Ext(target); !embed || ext == "" || ext == ".md" {
+					c.unresolved[strings.TrimSpace(target)] = struct{}{}
+				}
+				if match[1] < len(rest) {
+					result = appendText(result, rest[:match[1]], node.Marks)
+					rest = rest[match[1]:]
+					continue
+				}
+				break
+			}
+			result = appendText(result, rest[:match[0]], node.Marks)
+			result = append(result, noteLinkNode(note))
+			rest = rest[match[1]:]
+		}
+		result = appendText(result, rest, node.Marks)
+	}
+	return mergeTextNodes(result)
+}
+
+// noteLinkNode returns a note link to an imported note.
+func noteLinkNode(note *markdownNote) *Node {
+	return &Node{Type: noteLinkType, Attrs: map[string]interface{}{
+		"jotId": note.id,
+		"label": note.title,
+	}}
+}
+
+// appendText appends a text node unless text is empty.
+func appendText(nodes []*Node, text string, marks []Mark) []*Node {
+	if text == "" {
+		return nodes
+	}
+	return append(nodes, &Node{Type: "text", Text: text, Marks: marks})
+}
+
+// mergeTextNodes joins adjacent text nodes with the same marks.
+func mergeTextNodes(nodes []*Node) []*Node {
+	var merged []*Node
+	for _, node := range nodes {
+		if len(merged) > 0 {
+			last := merged[len(merged)-1]
+			if last.Type == "text" && node.Type == "text" && sameMarks(last.Marks, node.Marks) {
+				last.Text += node.Text
+				continue
+			}
+		}
+		merged = append(merged, node)
+	}
+	return merged
+}
+
+// sameMarks reports whether two text nodes have the same marks.
+func sameMarks(a, b []Mark) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Type != b[i].Type {
+			return false
+		}
+	}
+	return true
+}
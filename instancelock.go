@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// instanceLockName is the file in the data directory that the process
+// writing to the directory holds a lock on.
+const instanceLockName = "instance.lock"
+
+// instanceLockWait is how long to wait for the lock when another process may
+// hold it only for a moment, such as a command writing a jot.
+const instanceLockWait = time.Second
+
+var errInstanceRunning = errors.New("toJot is already running")
+
+// instanceLock is a lock on the data directory. The operating system releases
+// it when the process exits, so a crash does not leave it behind.
+type instanceLock struct {
+	file *os.File
+}
+
+// lockDataDir locks the data directory, trying again until wait has passed.
+// It returns errInstanceRunning if another process keeps holding the lock.
+func lockDataDir(dataDir string, wait time.Duration) (*instanceLock, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating data directory: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(dataDir, instanceLockName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening instance lock: %w", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		err := lockFile(file)
+		if err == nil {
+			return &instanceLock{file: file}, nil
+		}
+		if !errors.Is(err, errInstanceRunning) || time.Now().After(deadline) {
+			file.Close()
+			if errors.Is(err, errInstanceRunning) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("error locking data directory: %w", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Release unlocks the data directory.
+func (l *instanceLock) Release() error {
+	return l.file.Close()
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// jotLockVersion is the version of the JotLock envelope. The cipher is
+// versioned separately by the sealed box inside it.
+const jotLockVersion = 1
+
+var errJotLocked = errors.New("jot is locked")
+
+// JotLock is the envelope of a jot locked with its own password.
+type JotLock struct {
+	Version int        `json:"version"`
+	KDF     KDFParams  `json:"kdf"`
+	Box     *SealedBox `json:"box"`
+}
+
+// lockedContent is the part of a jot that is sealed by its lock.
+type lockedContent struct {
+	Content     *Node  `json:"content"`
+	TextContent string `json:"textContent"`
+}
+
+// jotLockAdditionalData binds a sealed jot to its id.
+func jotLockAdditionalData(jotID string) []byte {
+	return []byte("toJot locked jot " + jotID)
+}
+
+// key derives the key of the lock from its password.
+func (l *JotLock) key(password string) (*noteKey, error) {
+	if l.Version != jotLockVersion {
+		return nil, fmt.Errorf("unsupported jot lock version %d", l.Version)
+	}
+	return l.KDF.deriveKey(password)
+}
+
+// open decrypts the content of a locked jot.
+func (l *JotLock) open(jotID string, key *noteKey) (*lockedContent, error) {
+	var content lockedContent
+	if err := key.openJSON(l.Box, jotLockAdditionalData(jotID), &content); err != nil {
+		return nil, errWrongPassword
+	}
+	return &content, nil
+}
+
+// sealJot moves the content of jot into a new lock sealed with key, keeping
+// the KDF parameters the key was derived with.
+func sealJot(jot *Jot, kdf KDFParams, key *noteKey) error {
+	box, err := key.sealJSON(lockedContent{
+		Content:     jot.Content,
+		TextContent: jot.TextContent,
+	}, jotLockAdditionalData(jot.ID))
+	if err != nil {
+		return err
+	}
+	jot.Lock = &JotLock{Version: jotLockVersion, KDF: kdf, Box: box}
+	jot.Content = NewDocument()
+	jot.TextContent = ""
+	return nil
+}
+
+// sealJotLocked keeps the lock of a jot that is being saved. The lock is
+// taken from the stored jot, so it cannot be changed or removed by a save.
+// The new content of a locked jot is sealed with the key it was unlocked
+// with; saving a locked jot that is not unlocked fails. The caller must hold
+// the write lock.
+func (s *Store) sealJotLocked(jot *Jot) error {
+	stored, ok := s.jots[jot.ID]
+	if !ok {
+		stored, ok = s.trash[jot.ID]
+	}
+	if !ok || stored.Lock == nil {
+		jot.Lock = nil
+		return nil
+	}
+
+	key, ok := s.jotKeys[jot.ID]
+	if !ok {
+		return errJotLocked
+	}
+	return sealJot(jot, stored.Lock.KDF, key)
+}
+
+// LockJot encrypts the content of a jot with its own password. The text of
+// a locked jot is left out of search and the link and tag indexes, and its
+// revisions are deleted because they hold the plain content.
+func (s *Store) LockJot(id, password string) (*Jot, error) {
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	// The key is derived before taking the store lock, as the derivation is
+	// slow on purpose.
+	kdf, err := newKDFParams()
+	if err != nil {
+		return nil, err
+	}
+	key, err := kdf.deriveKey(password)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jot, ok := s.jots[id]
+	if !ok {
+		return nil, fmt.Errorf("jot %s not found", id)
+	}
+	if jot.Lock != nil {
+		return nil, fmt.Errorf("jot %s is already locked", id)
+	}
+
+	locked := jot.clone()
+	if err := sealJot(locked, kdf, key); err != nil {
+		return nil, err
+	}
+	if err := s.writeLocked(storeChange{jots: map[string]*Jot{id: locked}}); err != nil {
+		return nil, err
+	}
+	delete(s.jotKeys, id)
+	if err := s.revisions.Remove(id); err != nil {
+		fmt.Printf("Error removing revisions of locked jot %s: %v\n", id, err)
+	}
+	return locked.clone(), nil
+}
+
+// UnlockJot decrypts a locked jot for the rest of the session and returns it
+// with its content. It stays locked on disk, and later saves are sealed
+// again until it is relocked or the app exits.
+func (s *Store) UnlockJot(id, password string) (*Jot, error) {
+	key, err := s.jotLockKey(id, password)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jot, ok := s.jots[id]
+	if !ok {
+		return nil, fmt.Errorf("jot %s not found", id)
+	}
+	if jot.Lock == nil {
+		return jot.clone(), nil
+	}
+	if key == nil {
+		return nil, errJotLocked
+	}
+	content, err := jot.Lock.open(id, key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.jotKeys[id] = key
+	unlocked := jot.clone()
+	unlocked.Content = content.Content
+	unlocked.TextContent = content.TextContent
+	return unlocked, nil
+}
+
+// jotLockKey derives the key of a locked jot from its password without
+// holding the store lock, as the derivation is slow on purpose. The key is
+// nil if the jot is not locked.
+func (s *Store) jotLockKey(id, password string) (*noteKey, error) {
+	s.mu.RLock()
+	jot, ok := s.jots[id]
+	var lock *JotLock
+	if ok {
+		lock = jot.Lock
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("jot %s not found", id)
+	}
+	if lock == nil {
+		return nil, nil
+	}
+	return lock.key(password)
+}
+
+// RelockJot forgets the session key of a jot, so it must be unlocked again.
+func (s *Store) RelockJot(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.jotKeys, id)
+}
+
+// RemoveJotLock permanently decrypts a locked jot.
+func (s *Store) RemoveJotLock(id, password string) (*Jot, error) {
+	key, err := s.jotLockKey(id, password)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jot, ok := s.jots[id]
+	if !ok {
+		return nil, fmt.Errorf("jot %s not found", id)
+	}
+	if jot.Lock == nil {
+		return jot.clone(), nil
+	}
+	if key == nil {
+		return nil, errJotLocked
+	}
+	content, err := jot.Lock.open(id, key)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := jot.clone()
+	decrypted.Lock = nil
+	decrypted.Content = content.Content
+	decrypted.TextContent = content.TextContent
+	if err := s.writeLocked(storeChange{jots: map[string]*Jot{id: decrypted}}); err != nil {
+		return nil, err
+	}
+	delete(s.jotKeys, id)
+	return decrypted.clone(), nil
+}
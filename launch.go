@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// singleInstanceID identifies the running window to later launches, which
+// pass their arguments to it instead of opening a window of their own.
+const singleInstanceID = "com.wails.toJot"
+
+// launchFileExtensions are the files a launch can open, each as a new jot.
+var launchFileExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".txt":      true,
+}
+
+// launchRequest is what the arguments of a launch ask the window to do.
+type launchRequest struct {
+	// Command is "new" or "append" for those subcommands or links, "open"
+	// for a link to a jot, "capture" for quick capture, "file" for a file to
+	// open as a new jot, or "" to only bring the window to the front.
+	Command string
+	Title   string
+	Body    string
+	ID      string
+	Path    string
+}
+
+// parseLaunchArgs reads the arguments of a launch of the window: a new or
+// append subcommand sent on by the command line, the capture subcommand, a
+// tojot:// link, or a file to open. Relative paths are resolved against
+// workingDir. Arguments it does not understand, such as the flags macOS
+// passes to an app, are ignored; an invalid link is an error.
+func parseLaunchArgs(args []string, workingDir string) (launchRequest, error) {
+	if len(args) == 0 {
+		return launchRequest{}, nil
+	}
+	if args[0] == "capture" {
+		return launchRequest{Command: "capture"}, nil
+	}
+	if args[0] == "new" || args[0] == "append" {
+		flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		body := flags.String("body", "", "")
+		flags.Bool("json", false, "")
+		positional, err := parseInterspersed(flags, args[1:])
+		if err != nil {
+			return launchRequest{}, nil
+		}
+		if args[0] == "new" {
+			return launchRequest{Command: "new", Title: strings.Join(positional, " "), Body: *body}, nil
+		}
+		if len(positional) != 1 {
+			return launchRequest{}, nil
+		}
+		return launchRequest{Command: "append", ID: positional[0], Body: *body}, nil
+	}
+
+	for _, arg := range args {
+		if isDeepLink(arg) {
+			link, err := ParseDeepLink(arg)
+			if err != nil {
+				return launchRequest{}, err
+			}
+			return launchRequest{Command: link.Action, ID: link.ID, Title: link.Title, Body: link.Body}, nil
+		}
+		path := arg
+		// Desktop entries may pass files as file:// URLs.
+		if fileURL, err := url.Parse(arg); err == nil && fileURL.Scheme == "file" {
+			path = filepath.FromSlash(fileURL.Path)
+		}
+		if strings.HasPrefix(path, "-") || !launchFileExtensions[strings.ToLower(filepath.Ext(path))] {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workingDir, path)
+		}
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return launchRequest{Command: "file", Path: path}, nil
+		}
+	}
+	return launchRequest{}, nil
+}
+
+// runLaunch carries out a launch request and returns the jot it created or
+// changed, or nil if there is none.
+func (s *Store) runLaunch(request launchRequest) (*Jot, error) {
+	switch request.Command {
+	case "new":
+		title := strings.TrimSpace(request.Title)
+		if title == "" {
+			title = untitledJotTitle
+		}
+		return s.Save(&Jot{
+			ID:      uuid.NewString(),
+			Title:   title,
+			Content: MarkdownToDocument([]byte(request.Body)),
+		})
+	case "append":
+		return s.Append(request.ID, MarkdownToDocument([]byte(request.Body)).Content)
+	case "open":
+		if s.EncryptionStatus().Locked {
+			return nil, errStoreLocked
+		}
+		jot, ok := s.Get(request.ID)
+		if !ok {
+			return nil, fmt.Errorf("jot %s not found", request.ID)
+		}
+		return jot, nil
+	case "file":
+		jot, err := readLaunchFile(request.Path)
+		if err != nil {
+			return nil, err
+		}
+		return s.Save(jot)
+	}
+	return nil, nil
+}
+
+// readLaunchFile reads a Markdown or text file as a new jot titled with its
+// name.
+func readLaunchFile(path string) (*Jot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", path, err)
+	}
+	if info.Size() > maxImportFileSize {
+		return nil, fmt.Errorf("%s is larger than %d MB", filepath.Base(path), maxImportFileSize>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", path, err)
+	}
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("%s is not UTF-8 text", filepath.Base(path))
+	}
+
+	doc := NewDocument()
+	if strings.EqualFold(filepath.Ext(path), ".txt") {
+		text := strings.ReplaceAll(string(data), "\r\n", "\n")
+		for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+			doc.Content = append(doc.Content, textParagraph(line))
+		}
+	} else {
+		doc = MarkdownToDocument(data)
+	}
+	title := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return &Jot{
+		ID:        uuid.NewString(),
+		Title:     title,
+		Content:   doc,
+		CreatedAt: fileCreatedAt(info),
+		UpdatedAt: info.ModTime(),
+	}, nil
+}
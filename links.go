@@ -0,0 +1,196 @@
+package main
+
+import "sort"
+
+// noteLinkType is the TipTap node type used for links between jots.
+const noteLinkType = "noteLink"
+
+// NoteLink is a single noteLink node found in a jot.
+type NoteLink struct {
+	JotID string `json:"jotId"`
+	Label string `json:"label"`
+}
+
+// LinkGraphNode is a jot in the link graph.
+type LinkGraphNode struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// LinkGraphEdge is a link from one jot to another.
+type LinkGraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// LinkGraph contains all jots and the links between them, for a graph view.
+type LinkGraph struct {
+	Nodes []LinkGraphNode `json:"nodes"`
+	Edges []LinkGraphEdge `json:"edges"`
+}
+
+// ExtractNoteLinks returns all noteLink nodes in a document, in document
+// order.
+func ExtractNoteLinks(doc *Node) []NoteLink {
+	var links []NoteLink
+	doc.Walk(func(node *Node) bool {
+		if node.Type == noteLinkType {
+			if jotID := node.StringAttr("jotId"); jotID != "" {
+				links = append(links, NoteLink{
+					JotID: jotID,
+					Label: node.StringAttr("label"),
+				})
+			}
+			return false
+		}
+		return true
+	})
+	return links
+}
+
+// BrokenLink is a link to a jot that no longer exists.
+type BrokenLink struct {
+	SourceID    string `json:"sourceId"`
+	SourceTitle string `json:"sourceTitle"`
+	TargetID    string `json:"targetId"`
+	Label       string `json:"label"`
+}
+
+// relabelNoteLinks sets the label of every noteLink in doc for which title
+// returns a title. It returns the number of labels that changed.
+func relabelNoteLinks(doc *Node, title func(id string) (string, bool)) int {
+	changed := 0
+	doc.Walk(func(node *Node) bool {
+		if node.Type != noteLinkType {
+			return true
+		}
+		label, ok := title(node.StringAttr("jotId"))
+		if ok && node.StringAttr("label") != label {
+			if node.Attrs == nil {
+				node.Attrs = make(map[string]interface{})
+			}
+			node.Attrs["label"] = label
+			changed++
+		}
+		return false
+	})
+	return changed
+}
+
+// unlinkNoteLinks replaces every noteLink in doc whose target matches with a
+// plain text node containing its label. Links without a label are dropped,
+// since empty text nodes are not valid. It returns the number of links
+// replaced.
+func unlinkNoteLinks(doc *Node, matches func(id string) bool) int {
+	if doc == nil {
+		return 0
+	}
+
+	replaced := 0
+	content := doc.Content[:0]
+	for _, child := range doc.Content {
+		if child.Type == noteLinkType && matches(child.StringAttr("jotId")) {
+			replaced++
+			if label := child.StringAttr("label"); label != "" {
+				content = append(content, &Node{Type: "text", Text: label, Marks: child.Marks})
+			}
+			continue
+		}
+		replaced += unlinkNoteLinks(child, matches)
+		content = append(content, child)
+	}
+	doc.Content = content
+	return replaced
+}
+
+// LinkIndex keeps track of which jots link to which. It is not safe for
+// concurrent use; the store guards it with its own lock.
+type LinkIndex struct {
+	outgoing map[string]map[string]struct{}
+	incoming map[string]map[string]struct{}
+}
+
+// NewLinkIndex creates an empty link index.
+func NewLinkIndex() *LinkIndex {
+	return &LinkIndex{
+		outgoing: make(map[string]map[string]struct{}),
+		incoming: make(map[string]map[string]struct{}),
+	}
+}
+
+// Update replaces the outgoing links of a jot with the links in its content.
+func (l *LinkIndex) Update(jot *Jot) {
+	l.Remove(jot.ID)
+
+	targets := make(map[string]struct{})
+	for _, link := range ExtractNoteLinks(jot.Content) {
+		targets[link.JotID] = struct{}{}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	l.outgoing[jot.ID] = targets
+	for target := range targets {
+		if l.incoming[target] == nil {
+			l.incoming[target] = make(map[string]struct{})
+		}
+		l.incoming[target][jot.ID] = struct{}{}
+	}
+}
+
+// Remove drops the outgoing links of a jot. Links pointing at the jot are
+// kept, so they can still be reported as broken.
+func (l *LinkIndex) Remove(id string) {
+	for target := range l.outgoing[id] {
+		delete(l.incoming[target], id)
+		if len(l.incoming[target]) == 0 {
+			delete(l.incoming, target)
+		}
+	}
+	delete(l.outgoing, id)
+}
+
+// Backlinks returns the ids of the jots linking to id, sorted.
+func (l *LinkIndex) Backlinks(id string) []string {
+	return sortedKeys(l.incoming[id])
+}
+
+// Outgoing returns the ids of the jots that id links to, sorted.
+func (l *LinkIndex) Outgoing(id string) []string {
+	return sortedKeys(l.outgoing[id])
+}
+
+// Graph builds the link graph for the given jots. Edges pointing at jots
+// that are not in the list are left out.
+func (l *LinkIndex) Graph(jots []*Jot) LinkGraph {
+	graph := LinkGraph{
+		Nodes: make([]LinkGraphNode, 0, len(jots)),
+		Edges: []LinkGraphEdge{},
+	}
+
+	known := make(map[string]struct{}, len(jots))
+	for _, jot := range jots {
+		known[jot.ID] = struct{}{}
+		graph.Nodes = append(graph.Nodes, LinkGraphNode{ID: jot.ID, Title: jot.Title})
+	}
+
+	for _, jot := range jots {
+		for _, target := range l.Outgoing(jot.ID) {
+			if _, ok := known[target]; ok {
+				graph.Edges = append(graph.Edges, LinkGraphEdge{Source: jot.ID, Target: target})
+			}
+		}
+	}
+	return graph
+}
+
+// sortedKeys returns the keys of a set in sorted order.
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -2,30 +2,72 @@ package main
 
 import (
 	"embed"
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
+	"github.com/wailsapp/wails/v2/pkg/options/mac"
 )
 
 //go:embed all:frontend/dist
 var assets embed.FS
 
+// Size of the main window.
+const (
+	mainWidth  = 1024
+	mainHeight = 768
+)
+
 func main() {
+	// Subcommands run without opening the window
+	code, forward, ok := runCLI(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if ok {
+		os.Exit(code)
+	}
+	if forward != nil {
+		// Wails sends os.Args to the running window
+		os.Args = append(os.Args[:1], forward...)
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
+	if err := app.lockInstance(); err != nil && !errors.Is(err, errInstanceRunning) {
+		fmt.Printf("Error locking data directory: %v\n", err)
+	}
+
+	// A launch for quick capture opens a small window on top of the others
+	// that quits once the capture is saved
+	title, width, height := fmt.Sprintf("toJot - v%s", Version), mainWidth, mainHeight
+	app.capture.only = isCaptureLaunch(os.Args[1:])
+	if app.capture.only {
+		title, width, height = "toJot capture", captureWidth, captureHeight
+	}
 
 	// Create application with options
 	err := wails.Run(&options.App{
-		Title:  fmt.Sprintf("toJot - v%s", Version),
-		Width:  1024,
-		Height: 768,
+		Title:       title,
+		Width:       width,
+		Height:      height,
+		Frameless:   app.capture.only,
+		AlwaysOnTop: app.capture.only,
 		AssetServer: &assetserver.Options{
 			Assets: assets,
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
+		OnDomReady:       app.domReady,
+		OnBeforeClose:    app.beforeClose,
+		OnShutdown:       app.shutdown,
+		Mac: &mac.Options{
+			OnUrlOpen: app.onURLOpen,
+		},
+		SingleInstanceLock: &options.SingleInstanceLock{
+			UniqueId:               singleInstanceID,
+			OnSecondInstanceLaunch: app.onSecondInstanceLaunch,
+		},
 		Bind: []interface{}{
 			app,
 		},
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// markdownEscaper escapes the characters that would otherwise be read as
+// Markdown formatting inside text.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+)
+
+// WikiLink renders a note link as an Obsidian-style [[wikilink]].
+func WikiLink(link NoteLink) string {
+	return "[[" + link.Label + "]]"
+}
+
+// RenderMarkdown converts a TipTap document to Markdown. linkText renders
+// noteLink nodes; if it is nil they become [[wikilinks]].
+func RenderMarkdown(doc *Node, linkText func(link NoteLink) string) string {
+	if doc == nil {
+		return ""
+	}
+	if linkText == nil {
+		linkText = WikiLink
+	}
+	r := markdownRenderer{linkText: linkText}
+	lines := r.blocks(doc.Content)
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// RenderText converts a TipTap document to plain text. It keeps the block
+// structure, list markers and checkbox state, but no formatting. linkText
+// renders noteLink nodes; if it is nil they show their label.
+func RenderText(doc *Node, linkText func(link NoteLink) string) string {
+	if doc == nil {
+		return ""
+	}
+	if linkText == nil {
+		linkText = func(link NoteLink) string { return link.Label }
+	}
+	r := markdownRenderer{linkText: linkText, plain: true}
+	lines := r.blocks(doc.Content)
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// markdownRenderer renders blocks to lines of Markdown, or of plain text if
+// plain is set.
+type markdownRenderer struct {
+	linkText func(link NoteLink) string
+	plain    bool
+}
+
+// blocks renders a list of blocks separated by blank lines.
+func (r markdownRenderer) blocks(nodes []*Node) []string {
+	var lines []string
+	for i, node := range nodes {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, r.block(node)...)
+	}
+	return lines
+}
+
+// block renders a single block.
+func (r markdownRenderer) block(node *Node) []string {
+	switch node.Type {
+	case "paragraph":
+		return []string{r.inline(node.Content)}
+	case "heading":
+		if r.plain {
+			return []string{r.inline(node.Content)}
+		}
+		level := min(max(node.IntAttr("level", 1), 1), 6)
+		return []string{strings.Repeat("#", level) + " " + r.inline(node.Content)}
+	case "blockquote":
+		return prefixLines(r.blocks(node.Content), "> ", "> ")
+	case "horizontalRule":
+		return []string{"---"}
+	case "bulletList", "orderedList", "taskList":
+		return r.list(node)
+	default:
+		return r.blocks(node.Content)
+	}
+}
+
+// list renders a bullet, ordered or task list. Content after the first line
+// of an item is indented to line up with the item text.
+func (r markdownRenderer) list(node *Node) []string {
+	var lines []string
+	start := node.IntAttr("start", 1)
+	for i, item := range node.Content {
+		var marker string
+		switch node.Type {
+		case "orderedList":
+			marker = fmt.Sprintf("%d. ", start+i)
+		case "taskList":
+			marker = "- [ ] "
+			if item.BoolAttr("checked") {
+				marker = "- [x] "
+			}
+		default:
+			marker = "- "
+		}
+
+		itemLines := r.blocks(item.Content)
+		if len(itemLines) == 0 {
+			itemLines = []string{""}
+		}
+		lines = append(lines, prefixLines(itemLines, marker, strings.Repeat(" ", len(marker)))...)
+	}
+	return lines
+}
+
+// inline renders text and inline nodes.
+func (r markdownRenderer) inline(nodes []*Node) string {
+	var text strings.Builder
+	for _, node := range nodes {
+		switch node.Type {
+		case "text":
+			if r.plain {
+				text.WriteString(node.Text)
+				continue
+			}
+			text.WriteString(applyMarkdownMarks(markdownEscaper.Replace(node.Text), node.Marks))
+		case noteLinkType:
+			text.WriteString(r.linkText(NoteLink{
+				JotID: node.StringAttr("jotId"),
+				Label: node.StringAttr("label"),
+			}))
+		}
+	}
+	return text.String()
+}
+
+// applyMarkdownMarks wraps text in the Markdown for its marks. Surrounding
+// whitespace is kept outside the markers, since "** bold**" is not bold.
+func applyMarkdownMarks(text string, marks []Mark) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	leading := text[:strings.Index(text, trimmed)]
+	trailing := text[len(leading)+len(trimmed):]
+	for _, mark := range marks {
+		switch mark.Type {
+		case "bold":
+			trimmed = "**" + trimmed + "**"
+		case "italic":
+			trimmed = "*" + trimmed + "*"
+		}
+	}
+	return leading + trimmed + trailing
+}
+
+// prefixLines prefixes the first line with first and the others with rest.
+// Blank lines other than the first are left empty.
+func prefixLines(lines []string, first, rest string) []string {
+	prefixed := make([]string, len(lines))
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			prefixed[i] = first + line
+		case line == "":
+			prefixed[i] = strings.TrimRight(rest, " ")
+		default:
+			prefixed[i] = rest + line
+		}
+	}
+	return prefixed
+}
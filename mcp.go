@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// mcpProtocolVersions are the Model Context Protocol versions the server
+// speaks, newest first.
+var mcpProtocolVersions = []string{"2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// rpcMessage is a JSON-RPC request or notification. Notifications have no
+// id.
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is the answer to a request.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// mcpTool describes a tool to the client.
+type mcpTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+}
+
+// mcpContent is a text part of a tool result.
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// mcpToolResult is the result of a tool call. Failures of the tool itself
+// are results with IsError set, so the model can see them.
+type mcpToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError,omitempty"`
+}
+
+// mcpResource describes a jot as a resource.
+type mcpResource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType"`
+}
+
+// mcpResourceContents is the content of a read resource.
+type mcpResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// mcpServer serves the jots over the Model Context Protocol. It reads the
+// store file again whenever it changed on disk, so it sees the changes of the
+// window. Writes lock the data directory like the command line does; while
+// the window is open they go through its local API instead.
+type mcpServer struct {
+	cli      *cli
+	readOnly bool
+	dataDir  string
+	modTime  time.Time
+}
+
+// mcp runs the MCP server on stdin and stdout until stdin is closed.
+func (c *cli) mcp(args []string) error {
+	flags := c.flags("mcp")
+	readOnly := flags.Bool("read-only", false, "only offer the search and read tools")
+	args, err := c.parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError("mcp takes no arguments")
+	}
+	dataDir, err := DefaultDataDir()
+	if err != nil {
+		return err
+	}
+	server := &mcpServer{cli: c, readOnly: *readOnly, dataDir: dataDir}
+	if err := server.reload(); err != nil {
+		return err
+	}
+
+	// The store logs with fmt.Printf; stdout belongs to the protocol.
+	if c.stdout == os.Stdout {
+		os.Stdout = os.Stderr
+	}
+	return server.serve(c.stdin, c.stdout)
+}
+
+// serve reads one JSON-RPC message per line and writes the responses.
+func (m *mcpServer) serve(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64<<10), 2*maxImportFileSize)
+	encoder := json.NewEncoder(out)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if response := m.handle(line); response != nil {
+			if err := encoder.Encode(response); err != nil {
+				return fmt.Errorf("error writing response: %w", err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading request: %w", err)
+	}
+	return nil
+}
+
+// handle answers one message, returning nil for notifications.
+func (m *mcpServer) handle(line []byte) *rpcResponse {
+	var message rpcMessage
+	if err := json.Unmarshal(line, &message); err != nil {
+		return &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}}
+	}
+	if len(message.ID) == 0 {
+		// Notifications such as notifications/initialized need no answer.
+		return nil
+	}
+	response := &rpcResponse{JSONRPC: "2.0", ID: message.ID}
+	if message.JSONRPC != "2.0" || message.Method == "" {
+		response.Error = &rpcError{Code: rpcInvalidRequest, Message: "invalid JSON-RPC request"}
+		return response
+	}
+
+	result, err := m.call(message.Method, message.Params)
+	var callErr *rpcError
+	switch {
+	case errors.As(err, &callErr):
+		response.Error = callErr
+	case err != nil:
+		response.Error = &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	default:
+		response.Result = result
+	}
+	return response
+}
+
+// call runs a method.
+func (m *mcpServer) call(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "initialize":
+		var request struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(params, &request)
+		version := mcpProtocolVersions[0]
+		for _, supported := range mcpProtocolVersions {
+			if request.ProtocolVersion == supported {
+				version = supported
+			}
+		}
+		return map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities": map[string]interface{}{
+				"tools":     map[string]interface{}{},
+				"resources": map[string]interface{}{},
+			},
+			"serverInfo": map[string]interface{}{"name": "toJot", "version": Version},
+			"instructions": "toJot holds the user's notes, called jots. Search them before reading, " +
+				"and refer to a jot by its id.",
+		}, nil
+	case "ping":
+		return map[string]interface{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": m.tools()}, nil
+	case "tools/call":
+		var request struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(params, &request); err != nil {
+			return nil, err
+		}
+		return m.callTool(request.Name, request.Arguments)
+	case "resources/list":
+		if err := m.reload(); err != nil {
+			return nil, err
+		}
+		resources := []mcpResource{}
+		for _, jot := range m.cli.store.ListJots() {
+			resources = append(resources, mcpResource{
+				URI:         JotDeepLink(jot.ID),
+				Name:        jot.Title,
+				Description: "Updated " + jot.UpdatedAt.Format(time.RFC3339),
+				MimeType:    "text/markdown",
+			})
+		}
+		return map[string]interface{}{"resources": resources}, nil
+	case "resources/templates/list":
+		return map[string]interface{}{"resourceTemplates": []map[string]string{{
+			"uriTemplate": deepLinkScheme + "://open/{id}",
+			"name":        "Jot",
+			"description": "A jot as Markdown, by id",
+			"mimeType":    "text/markdown",
+		}}}, nil
+	case "resources/read":
+		var request struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(params, &request); err != nil {
+			return nil, err
+		}
+		link, err := ParseDeepLink(request.URI)
+		if err != nil {
+			return nil, err
+		}
+		if link.Action != "open" {
+			return nil, fmt.Errorf("not a jot resource: %s", request.URI)
+		}
+		markdown, err := m.readMarkdown(link.ID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"contents": []mcpResourceContents{{
+			URI:      request.URI,
+			MimeType: "text/markdown",
+			Text:     markdown,
+		}}}, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", method)}
+}
+
+// tools returns the tools offered, without the writing ones in read-only
+// mode.
+func (m *mcpServer) tools() []mcpTool {
+	readOnly := map[string]interface{}{"readOnlyHint": true}
+	tools := []mcpTool{
+		{
+			Name:        "search_jots",
+			Description: "Search the titles and text of jots. Every word of the query must match. Returns ids, titles and snippets.",
+			InputSchema: mcpSchema(map[string]interface{}{
+				"query": map[string]interface{}{"type": "string", "description": "Words to search for"},
+				"limit": map[string]interface{}{"type": "integer", "description": "Maximum number of results, 20 by default"},
+			}, "query"),
+			Annotations: readOnly,
+		},
+		{
+			Name:        "read_jot",
+			Description: "Read a jot by id, as Markdown or as its TipTap JSON document.",
+			InputSchema: mcpSchema(map[string]interface{}{
+				"id":     map[string]interface{}{"type": "string"},
+				"format": map[string]interface{}{"type": "string", "enum": []string{"markdown", "json"}},
+			}, "id"),
+			Annotations: readOnly,
+		},
+	}
+	if m.readOnly {
+		return tools
+	}
+	return append(tools,
+		mcpTool{
+			Name:        "create_jot",
+			Description: "Create a jot from a title and a Markdown body. Returns the id of the new jot.",
+			InputSchema: mcpSchema(map[string]interface{}{
+				"title":    map[string]interface{}{"type": "string"},
+				"markdown": map[string]interface{}{"type": "string"},
+			}, "title"),
+		},
+		mcpTool{
+			Name:        "append_to_jot",
+			Description: "Add Markdown to the end of a jot.",
+			InputSchema: mcpSchema(map[string]interface{}{
+				"id":       map[string]interface{}{"type": "string"},
+				"markdown": map[string]interface{}{"type": "string"},
+			}, "id", "markdown"),
+		},
+	)
+}
+
+// mcpSchema returns the JSON schema of an object with the given properties.
+func mcpSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// callTool runs a tool. Unknown tools and malformed arguments are protocol
+// errors; everything else is reported in the result.
+func (m *mcpServer) callTool(name string, arguments json.RawMessage) (*mcpToolResult, error) {
+	var args struct {
+		Query    string `json:"query"`
+		Limit    int    `json:"limit"`
+		ID       string `json:"id"`
+		Format   string `json:"format"`
+		Title    string `json:"title"`
+		Markdown string `json:"markdown"`
+	}
+	if len(arguments) > 0 {
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+
+	var text string
+	var err error
+	switch name {
+	case "search_jots":
+		text, err = m.search(args.Query, args.Limit)
+	case "read_jot":
+		if args.Format == "json" {
+			text, err = m.readJSON(args.ID)
+		} else {
+			text, err = m.readMarkdown(args.ID)
+		}
+	case "create_jot", "append_to_jot":
+		if m.readOnly {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("tool %s is not available in read-only mode", name)}
+		}
+		var jot *Jot
+		if name == "create_jot" {
+			if jot, err = m.create(args.Title, args.Markdown); err == nil {
+				text = fmt.Sprintf("Created %q with id %s", jot.Title, jot.ID)
+			}
+		} else if jot, err = m.append(args.ID, args.Markdown); err == nil {
+			text = fmt.Sprintf("Appended to %q (%s)", jot.Title, jot.ID)
+		}
+	default:
+		return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown tool %q", name)}
+	}
+	if err != nil {
+		return &mcpToolResult{Content: []mcpContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	return &mcpToolResult{Content: []mcpContent{{Type: "text", Text: text}}}, nil
+}
+
+func (m *mcpServer) search(query string, limit int) (string, error) {
+	if query == "" {
+		return "", fmt.Errorf("query is empty")
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if err := m.reload(); err != nil {
+		return "", err
+	}
+	results := m.cli.store.Search(query, limit)
+	data, err := json.MarshalIndent(results, "", "  ")
+	return string(data), err
+}
+
+// jot returns a jot that is not locked with its own password.
+func (m *mcpServer) jot(id string) (*Jot, error) {
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	jot, ok := m.cli.store.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("jot %s not found", id)
+	}
+	if jot.Lock != nil {
+		return nil, errJotLocked
+	}
+	return jot, nil
+}
+
+func (m *mcpServer) readMarkdown(id string) (string, error) {
+	jot, err := m.jot(id)
+	if err != nil {
+		return "", err
+	}
+	return m.cli.store.Export([]string{jot.ID}, ExportOptions{Format: ExportMarkdown})
+}
+
+func (m *mcpServer) readJSON(id string) (string, error) {
+	jot, err := m.jot(id)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(jot, "", "  ")
+	return string(data), err
+}
+
+func (m *mcpServer) create(title, markdown string) (*Jot, error) {
+	request := launchRequest{Command: "new", Title: title, Body: markdown}
+	return m.write(request, func(client *apiClient) (*Jot, error) {
+		return client.post("/jots", apiJotRequest{Title: request.Title, Markdown: markdown})
+	})
+}
+
+func (m *mcpServer) append(id, markdown string) (*Jot, error) {
+	if len(MarkdownToDocument([]byte(markdown)).Content) == 0 {
+		return nil, fmt.Errorf("nothing to append")
+	}
+	request := launchRequest{Command: "append", ID: id, Body: markdown}
+	return m.write(request, func(client *apiClient) (*Jot, error) {
+		return client.post("/jots/"+id+"/append", apiJotRequest{Markdown: markdown})
+	})
+}
+
+// write carries out a change with the data directory locked, after reading
+// the latest store. While the window holds the lock, the change is sent to
+// its local API with remote.
+func (m *mcpServer) write(request launchRequest, remote func(*apiClient) (*Jot, error)) (*Jot, error) {
+	lock, err := lockDataDir(m.dataDir, instanceLockWait)
+	if errors.Is(err, errInstanceRunning) {
+		client, err := newLocalAPIClient(m.dataDir)
+		if err != nil {
+			return nil, err
+		}
+		return remote(client)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	if request.Command == "append" {
+		if _, err := m.jot(request.ID); err != nil {
+			return nil, err
+		}
+	}
+	return m.cli.store.runLaunch(request)
+}
+
+// reload opens the store again if its file changed since it was last read.
+func (m *mcpServer) reload() error {
+	var modTime time.Time
+	if m.cli.store != nil {
+		if info, err := os.Stat(m.cli.store.path); err == nil {
+			modTime = info.ModTime()
+		}
+		if modTime.Equal(m.modTime) {
+			return nil
+		}
+	}
+	if err := m.cli.open(nil); err != nil {
+		return err
+	}
+	m.modTime = modTime
+	return nil
+}
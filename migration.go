@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// browserStoreMigration is the id of the marker of the move of jots from the
+// browser database into the Go store.
+const browserStoreMigration = "browser-store"
+
+// Migration is the marker of a one-time data migration.
+type Migration struct {
+	StartedAt   time.Time  `json:"startedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	// Total is the number of chunks of the migration and Chunks the number
+	// applied in order from the first, so an interrupted migration can go on
+	// where it stopped.
+	Total  int `json:"total"`
+	Chunks int `json:"chunks"`
+	// Imported and Updated count the jots added and replaced.
+	Imported int `json:"imported"`
+	Updated  int `json:"updated"`
+}
+
+// BrowserStoreChunk is one part of the jots in the browser database.
+type BrowserStoreChunk struct {
+	// Index is the position of the chunk, from 0, and Total the number of
+	// chunks the jots were split into.
+	Index int           `json:"index"`
+	Total int           `json:"total"`
+	Jots  []*BrowserJot `json:"jots"`
+}
+
+// BrowserJot is a jot as stored in the browser database. Its content is
+// decoded separately, so one malformed document does not fail the chunk.
+type BrowserJot struct {
+	ID        string      `json:"id"`
+	Title     string      `json:"title"`
+	Content   interface{} `json:"content"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
+// Migration returns a copy of the marker of a migration, or nil if it never
+// started.
+func (s *Store) Migration(id string) *Migration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	marker, ok := s.migrations[id]
+	if !ok {
+		return nil
+	}
+	clone := *marker
+	return &clone
+}
+
+// ImportBrowserChunk adds a chunk of jots from the browser database. A jot
+// is added if its id is new and replaces the stored jot only if it was
+// updated more recently, so sending a chunk again changes nothing and an
+// interrupted migration can be repeated safely. Jots in the trash or locked
+// with their own password are left alone. Jots whose content is not a valid
+// document are reported as skipped. The chunk and the migration marker are
+// written together; the marker is complete once every chunk was applied.
+func (s *Store) ImportBrowserChunk(chunk BrowserStoreChunk) (*ImportReport, error) {
+	if chunk.Total < 1 || chunk.Index < 0 || chunk.Index >= chunk.Total {
+		return nil, fmt.Errorf("invalid chunk %d of %d", chunk.Index, chunk.Total)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := newImportReport(false)
+	incoming := make(map[string]*Jot, len(chunk.Jots))
+	for _, browserJot := range chunk.Jots {
+		jot, err := browserJot.jot()
+		if err != nil {
+			report.skip(browserJot.label(), "%v", err)
+			continue
+		}
+		report.repair(browserJot.label(), jot)
+		if previous, ok := incoming[jot.ID]; ok && !jot.UpdatedAt.After(previous.UpdatedAt) {
+			continue
+		}
+		incoming[jot.ID] = jot
+	}
+
+	change := storeChange{jots: make(map[string]*Jot)}
+	for id, jot := range incoming {
+		if _, ok := s.trash[id]; ok {
+			continue
+		}
+		existing, ok := s.jots[id]
+		if !ok {
+			change.jots[id] = jot
+			report.Imported++
+			report.IDs = append(report.IDs, id)
+			continue
+		}
+		if !jot.UpdatedAt.After(existing.UpdatedAt) {
+			continue
+		}
+		if existing.Lock != nil {
+			report.skip(jot.Title, "the stored jot is locked")
+			continue
+		}
+		jot.FolderID = existing.FolderID
+		jot.Position = existing.Position
+		jot.Pinned = existing.Pinned
+		jot.PinOrder = existing.PinOrder
+		jot.Favourite = existing.Favourite
+		jot.DailyDate = existing.DailyDate
+		if existing.Source != nil {
+			source := *existing.Source
+			jot.Source = &source
+		}
+		change.jots[id] = jot
+		report.Updated++
+		report.IDs = append(report.IDs, id)
+	}
+
+	marker := &Migration{StartedAt: time.Now()}
+	if previous, ok := s.migrations[browserStoreMigration]; ok {
+		*marker = *previous
+	}
+	// A dump split differently than before starts the count over, since
+	// its chunks hold other jots.
+	if marker.Total != chunk.Total {
+		marker.Total, marker.Chunks = chunk.Total, 0
+	}
+	if chunk.Index == marker.Chunks {
+		marker.Chunks++
+	}
+	marker.Imported += report.Imported
+	marker.Updated += report.Updated
+	if marker.Chunks == marker.Total && marker.CompletedAt == nil {
+		now := time.Now()
+		marker.CompletedAt = &now
+	}
+	change.migrations = map[string]*Migration{browserStoreMigration: marker}
+
+	if err := s.writeLocked(change); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// jot converts and checks a jot from the browser database.
+func (b *BrowserJot) jot() (*Jot, error) {
+	if b == nil || strings.TrimSpace(b.ID) == "" {
+		return nil, fmt.Errorf("jot has no id")
+	}
+	data, err := json.Marshal(b.Content)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding content: %w", err)
+	}
+	var doc *Node
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("content is not a document: %w", err)
+	}
+	if doc == nil || doc.Type != "doc" {
+		return nil, fmt.Errorf("content is not a document")
+	}
+
+	title := strings.TrimSpace(b.Title)
+	if title == "" {
+		title = untitledJotTitle
+	}
+	jot := &Jot{
+		ID:        b.ID,
+		Title:     title,
+		Content:   doc,
+		CreatedAt: b.CreatedAt,
+		UpdatedAt: b.UpdatedAt,
+	}
+	if jot.UpdatedAt.IsZero() {
+		jot.UpdatedAt = jot.CreatedAt
+	}
+	if jot.CreatedAt.IsZero() {
+		jot.CreatedAt = jot.UpdatedAt
+	}
+	if jot.CreatedAt.IsZero() {
+		return nil, fmt.Errorf("jot has no timestamps")
+	}
+	return jot, nil
+}
+
+// label names a jot from the browser database in a report.
+func (b *BrowserJot) label() string {
+	if b == nil {
+		return "(empty)"
+	}
+	if b.Title != "" {
+		return b.Title
+	}
+	return b.ID
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// PDF page sizes supported by the export.
+var pdfPageSizes = map[string]bool{
+	"A3":     true,
+	"A4":     true,
+	"A5":     true,
+	"Letter": true,
+	"Legal":  true,
+}
+
+// PDFOptions controls the layout of a PDF export. Margins are in
+// millimetres.
+type PDFOptions struct {
+	PageSize string  `json:"pageSize"`
+	Margin   float64 `json:"margin"`
+}
+
+// DefaultPDFOptions are used for options that are not set.
+var DefaultPDFOptions = PDFOptions{
+	PageSize: "A4",
+	Margin:   20,
+}
+
+//go:embed fonts/DejaVuSansCondensed*.ttf
+var pdfFonts embed.FS
+
+// pdfFontFiles are the styles of the PDF font. A Unicode font is embedded
+// because the standard PDF fonts only hold Western European text; scripts
+// it has no glyphs for, such as Chinese, are still left blank.
+var pdfFontFiles = map[string]string{
+	"":   "fonts/DejaVuSansCondensed.ttf",
+	"B":  "fonts/DejaVuSansCondensed-Bold.ttf",
+	"I":  "fonts/DejaVuSansCondensed-Oblique.ttf",
+	"BI": "fonts/DejaVuSansCondensed-BoldOblique.ttf",
+}
+
+// Font sizes and spacing of the PDF layout, in points and millimetres.
+const (
+	pdfFont          = "DejaVu"
+	pdfBodySize      = 11
+	pdfLineHeight    = 5.5
+	pdfBlockSpacing  = 2.5
+	pdfIndent        = 7
+	pdfHeaderSize    = 8
+	pdfCheckboxSize  = 3
+	pdfHeaderSpacing = 8
+	// pdfMinMargin leaves room for the header and the page number, which
+	// are drawn in the margins.
+	pdfMinMargin = 10
+)
+
+// pdfHeadingSizes are the font sizes of heading levels 1 to 6.
+var pdfHeadingSizes = [...]float64{20, 16, 14, 12, 11, 11}
+
+// validate fills in defaults and checks the options.
+func (o *PDFOptions) validate() error {
+	if o.PageSize == "" {
+		o.PageSize = DefaultPDFOptions.PageSize
+	}
+	if o.Margin == 0 {
+		o.Margin = DefaultPDFOptions.Margin
+	}
+	if !pdfPageSizes[o.PageSize] {
+		return fmt.Errorf("unknown page size %q", o.PageSize)
+	}
+	if o.Margin < pdfMinMargin || o.Margin > 50 {
+		return fmt.Errorf("margin must be between %d and 50 mm", pdfMinMargin)
+	}
+	return nil
+}
+
+// ExportPDF renders the jots with the given ids, in that order, as one PDF.
+// Jots locked with their own password cannot be exported.
+// Every jot starts on a new page with a header showing its title and date.
+// When more than one jot is exported, the PDF starts with a table of
+// contents, and note links between exported jots jump to their page.
+func (s *Store) ExportPDF(ids []string, options PDFOptions) ([]byte, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no jots to export")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jots := make([]*Jot, 0, len(ids))
+	for _, id := range ids {
+		jot, ok := s.jots[id]
+		if !ok {
+			return nil, fmt.Errorf("jot %s not found", id)
+		}
+		if jot.Lock != nil {
+			return nil, fmt.Errorf("%w: %s", errJotLocked, jot.Title)
+		}
+		jots = append(jots, jot)
+	}
+	return renderPDF(jots, s.titleLocked, options)
+}
+
+// pdfWriter lays out TipTap documents on PDF pages.
+type pdfWriter struct {
+	pdf   *gofpdf.Fpdf
+	title func(id string) (string, bool)
+	// links are the internal links to the start of each exported jot.
+	links map[string]int
+	// margin is the left margin of the page, before any indentation.
+	margin float64
+	// quoted is set while writing a blockquote, whose text is grey.
+	quoted bool
+}
+
+// renderPDF renders jots to a PDF document.
+func renderPDF(jots []*Jot, title func(id string) (string, bool), options PDFOptions) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", options.PageSize, "")
+	pdf.SetMargins(options.Margin, options.Margin, options.Margin)
+	pdf.SetAutoPageBreak(true, options.Margin)
+	pdf.SetCreator("toJot", true)
+	pdf.AliasNbPages("")
+	for style, name := range pdfFontFiles {
+		font, err := pdfFonts.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("error reading PDF font: %w", err)
+		}
+		pdf.AddUTF8FontFromBytes(pdfFont, style, font)
+	}
+	if err := pdf.Error(); err != nil {
+		return nil, fmt.Errorf("error loading PDF font: %w", err)
+	}
+
+	w := &pdfWriter{
+		pdf:    pdf,
+		title:  title,
+		links:  make(map[string]int, len(jots)),
+		margin: options.Margin,
+	}
+	for _, jot := range jots {
+		w.links[jot.ID] = pdf.AddLink()
+	}
+	if len(jots) == 1 {
+		pdf.SetTitle(jots[0].Title, true)
+	} else {
+		pdf.SetTitle("toJot export", true)
+	}
+
+	headerTitle, headerDate := "", ""
+	pdf.SetHeaderFuncMode(func() {
+		pageWidth, _ := pdf.GetPageSize()
+		pdf.SetFont(pdfFont, "", pdfHeaderSize)
+		pdf.SetTextColor(110, 110, 110)
+		width := pageWidth - 2*options.Margin
+		pdf.SetXY(options.Margin, options.Margin-pdfHeaderSpacing)
+		pdf.CellFormat(width*0.7, 4, headerTitle, "", 0, "L", false, 0, "")
+		pdf.CellFormat(width*0.3, 4, headerDate, "", 0, "R", false, 0, "")
+		pdf.SetDrawColor(200, 200, 200)
+		pdf.Line(options.Margin, options.Margin-3, pageWidth-options.Margin, options.Margin-3)
+		left, _, _, _ := pdf.GetMargins()
+		pdf.SetXY(left, options.Margin)
+	}, false)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-options.Margin + 4)
+		pdf.SetFont(pdfFont, "", pdfHeaderSize)
+		pdf.SetTextColor(110, 110, 110)
+		pdf.CellFormat(0, 4, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	if len(jots) > 1 {
+		headerTitle, headerDate = "Contents", time.Now().Format(time.DateOnly)
+		w.tableOfContents(jots)
+	}
+	for i, jot := range jots {
+		headerTitle, headerDate = jot.Title, jot.UpdatedAt.Format(time.DateOnly)
+		pdf.AddPage()
+		pdf.SetLink(w.links[jot.ID], -1, pdf.PageNo())
+		pdf.RegisterAlias(tocAlias(i), fmt.Sprint(pdf.PageNo()))
+		w.heading(jot.Title, 1)
+		if jot.Content != nil {
+			w.blocks(jot.Content.Content)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, fmt.Errorf("error creating PDF: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// tocAlias is the placeholder for the page number of the i-th jot, filled in
+// once the jot has been laid out.
+func tocAlias(i int) string {
+	return fmt.Sprintf("{p%d}", i)
+}
+
+// tableOfContents writes a page listing the jots with their page numbers.
+func (w *pdfWriter) tableOfContents(jots []*Jot) {
+	w.pdf.AddPage()
+	w.heading("Contents", 1)
+	w.pdf.SetFont(pdfFont, "", pdfBodySize)
+	pageWidth, _ := w.pdf.GetPageSize()
+	width := pageWidth - 2*w.margin
+	for i, jot := range jots {
+		w.pdf.CellFormat(width-15, pdfLineHeight+1, jot.Title, "", 0, "L", false, w.links[jot.ID], "")
+		w.pdf.CellFormat(15, pdfLineHeight+1, tocAlias(i), "", 1, "R", false, w.links[jot.ID], "")
+	}
+}
+
+// heading writes a heading of the given level.
+func (w *pdfWriter) heading(text string, level int) {
+	level = min(max(level, 1), len(pdfHeadingSizes))
+	size := pdfHeadingSizes[level-1]
+	w.pdf.SetFont(pdfFont, "B", size)
+	w.pdf.Write(size*0.5, text)
+	w.pdf.Ln(size*0.5 + pdfBlockSpacing)
+}
+
+// blocks writes a list of blocks.
+func (w *pdfWriter) blocks(nodes []*Node) {
+	for _, node := range nodes {
+		w.block(node)
+	}
+}
+
+// block writes a single block.
+func (w *pdfWriter) block(node *Node) {
+	switch node.Type {
+	case "paragraph":
+		w.inline(node.Content, pdfBodySize, "")
+		w.pdf.Ln(pdfLineHeight + pdfBlockSpacing)
+	case "heading":
+		level := min(max(node.IntAttr("level", 1), 1), len(pdfHeadingSizes))
+		size := pdfHeadingSizes[level-1]
+		w.pdf.Ln(pdfBlockSpacing)
+		w.inline(node.Content, size, "B")
+		w.pdf.Ln(size*0.5 + pdfBlockSpacing)
+	case "blockquote":
+		w.blockquote(node)
+	case "horizontalRule":
+		left, _, right, _ := w.pdf.GetMargins()
+		pageWidth, _ := w.pdf.GetPageSize()
+		y := w.pdf.GetY() + pdfBlockSpacing
+		w.pdf.SetDrawColor(200, 200, 200)
+		w.pdf.Line(left, y, pageWidth-right, y)
+		w.pdf.SetY(y + 2*pdfBlockSpacing)
+	case "bulletList", "orderedList", "taskList":
+		w.list(node)
+	default:
+		w.blocks(node.Content)
+	}
+}
+
+// blockquote writes quoted blocks indented and grey, with a bar on the left
+// if the quote fits on one page.
+func (w *pdfWriter) blockquote(node *Node) {
+	left, _, _, _ := w.pdf.GetMargins()
+	startPage, startY := w.pdf.PageNo(), w.pdf.GetY()
+
+	w.pdf.SetLeftMargin(left + pdfIndent)
+	w.pdf.SetX(left + pdfIndent)
+	quoted := w.quoted
+	w.quoted = true
+	w.setTextColor()
+	w.blocks(node.Content)
+	w.quoted = quoted
+	w.setTextColor()
+	w.pdf.SetLeftMargin(left)
+	w.pdf.SetX(left)
+
+	if w.pdf.PageNo() == startPage {
+		w.pdf.SetDrawColor(200, 200, 200)
+		w.pdf.SetLineWidth(0.8)
+		w.pdf.Line(left+2, startY, left+2, w.pdf.GetY()-pdfBlockSpacing)
+		w.pdf.SetLineWidth(0.2)
+	}
+}
+
+// list writes the items of a list, with the marker in the indentation.
+func (w *pdfWriter) list(node *Node) {
+	left, _, _, _ := w.pdf.GetMargins()
+	start := node.IntAttr("start", 1)
+	w.pdf.SetLeftMargin(left + pdfIndent)
+
+	for i, item := range node.Content {
+		w.pdf.SetFont(pdfFont, "", pdfBodySize)
+		y := w.pdf.GetY()
+		switch node.Type {
+		case "orderedList":
+			w.pdf.SetX(left)
+			w.pdf.CellFormat(pdfIndent-1, pdfLineHeight, fmt.Sprintf("%d.", start+i), "", 0, "R", false, 0, "")
+		case "taskList":
+			w.pdf.SetDrawColor(90, 90, 90)
+			top := y + (pdfLineHeight-pdfCheckboxSize)/2
+			w.pdf.Rect(left+1, top, pdfCheckboxSize, pdfCheckboxSize, "D")
+			if item.BoolAttr("checked") {
+				w.pdf.Line(left+1.6, top+1.6, left+2.4, top+2.5)
+				w.pdf.Line(left+2.4, top+2.5, left+3.6, top+0.5)
+			}
+		default:
+			w.pdf.SetX(left)
+			w.pdf.CellFormat(pdfIndent-1, pdfLineHeight, "•", "", 0, "R", false, 0, "")
+		}
+		w.pdf.SetXY(left+pdfIndent, y)
+		w.listItem(item)
+	}
+
+	w.pdf.SetLeftMargin(left)
+	w.pdf.SetX(left)
+}
+
+// listItem writes the blocks of a list item without the spacing after the
+// last paragraph, so items stay close together.
+func (w *pdfWriter) listItem(item *Node) {
+	for i, child := range item.Content {
+		if child.Type == "paragraph" {
+			w.inline(child.Content, pdfBodySize, "")
+			spacing := pdfBlockSpacing
+			if i == len(item.Content)-1 {
+				spacing = 0.5
+			}
+			w.pdf.Ln(pdfLineHeight + spacing)
+			continue
+		}
+		w.block(child)
+	}
+	if len(item.Content) == 0 {
+		w.pdf.Ln(pdfLineHeight + 0.5)
+	}
+}
+
+// setTextColor sets the text color for normal or quoted text.
+func (w *pdfWriter) setTextColor() {
+	if w.quoted {
+		w.pdf.SetTextColor(90, 90, 90)
+	} else {
+		w.pdf.SetTextColor(0, 0, 0)
+	}
+}
+
+// inline writes text and note links in the given base style, switching fonts
+// for bold and italic. Links to exported jots jump to their page.
+func (w *pdfWriter) inline(nodes []*Node, size float64, baseStyle string) {
+	height := size * 0.5
+	for _, node := range nodes {
+		switch node.Type {
+		case "text":
+			style := baseStyle
+			for _, mark := range node.Marks {
+				switch mark.Type {
+				case "bold":
+					if !strings.Contains(style, "B") {
+						style += "B"
+					}
+				case "italic":
+					style += "I"
+				}
+			}
+			w.pdf.SetFont(pdfFont, style, size)
+			w.pdf.Write(height, node.Text)
+		case noteLinkType:
+			id := node.StringAttr("jotId")
+			text, ok := w.title(id)
+			if !ok {
+				text = node.StringAttr("label")
+			}
+			w.pdf.SetFont(pdfFont, "U", size)
+			if link, ok := w.links[id]; ok {
+				w.pdf.SetTextColor(9, 105, 218)
+				w.pdf.WriteLinkID(height, text, link)
+				w.setTextColor()
+			} else {
+				w.pdf.Write(height, text)
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		w.pdf.SetFont(pdfFont, baseStyle, size)
+	}
+}
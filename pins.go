@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ListJots returns all jots with pinned jots first, in their manual order,
+// followed by the other jots sorted by updatedAt descending.
+func (s *Store) ListJots() []JotSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jots := s.sortedLocked()
+	sortPinnedFirst(jots)
+	summaries := make([]JotSummary, 0, len(jots))
+	for _, jot := range jots {
+		summaries = append(summaries, jot.summary())
+	}
+	return summaries
+}
+
+// Favourites returns the favourite jots, pinned ones first.
+func (s *Store) Favourites() []JotSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var jots []*Jot
+	for _, jot := range s.sortedLocked() {
+		if jot.Favourite {
+			jots = append(jots, jot)
+		}
+	}
+	sortPinnedFirst(jots)
+	summaries := make([]JotSummary, 0, len(jots))
+	for _, jot := range jots {
+		summaries = append(summaries, jot.summary())
+	}
+	return summaries
+}
+
+// sortPinnedFirst moves pinned jots to the front in their manual order. The
+// order of the other jots is kept.
+func sortPinnedFirst(jots []*Jot) {
+	sort.SliceStable(jots, func(i, j int) bool {
+		if jots[i].Pinned != jots[j].Pinned {
+			return jots[i].Pinned
+		}
+		if jots[i].Pinned {
+			return jots[i].PinOrder < jots[j].PinOrder
+		}
+		return false
+	})
+}
+
+// SetPinned pins or unpins a jot. Newly pinned jots go to the end of the
+// pinned list.
+func (s *Store) SetPinned(id string, pinned bool) (*Jot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jot, ok := s.jots[id]
+	if !ok {
+		return nil, fmt.Errorf("jot %s not found", id)
+	}
+	if jot.Pinned == pinned {
+		return jot.clone(), nil
+	}
+
+	updated := jot.clone()
+	updated.Pinned = pinned
+	updated.PinOrder = 0
+	if pinned {
+		for _, other := range s.jots {
+			if other.Pinned && other.PinOrder >= updated.PinOrder {
+				updated.PinOrder = other.PinOrder + 1
+			}
+		}
+	}
+	if err := s.writeLocked(storeChange{jots: map[string]*Jot{id: updated}}); err != nil {
+		return nil, err
+	}
+	return updated.clone(), nil
+}
+
+// SetFavourite marks or unmarks a jot as favourite.
+func (s *Store) SetFavourite(id string, favourite bool) (*Jot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jot, ok := s.jots[id]
+	if !ok {
+		return nil, fmt.Errorf("jot %s not found", id)
+	}
+	if jot.Favourite == favourite {
+		return jot.clone(), nil
+	}
+
+	updated := jot.clone()
+	updated.Favourite = favourite
+	if err := s.writeLocked(storeChange{jots: map[string]*Jot{id: updated}}); err != nil {
+		return nil, err
+	}
+	return updated.clone(), nil
+}
+
+// ReorderPinned sets the manual order of the pinned jots. Pinned jots not in
+// ids keep their relative order after the listed ones.
+func (s *Store) ReorderPinned(ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pinned []*Jot
+	for _, jot := range s.jots {
+		if jot.Pinned {
+			pinned = append(pinned, jot)
+		}
+	}
+	sortPinnedFirst(pinned)
+
+	ordered := make([]*Jot, 0, len(pinned))
+	listed := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		jot, ok := s.jots[id]
+		if !ok || !jot.Pinned {
+			return fmt.Errorf("jot %s is not pinned", id)
+		}
+		if _, ok := listed[id]; !ok {
+			listed[id] = struct{}{}
+			ordered = append(ordered, jot)
+		}
+	}
+	for _, jot := range pinned {
+		if _, ok := listed[jot.ID]; !ok {
+			ordered = append(ordered, jot)
+		}
+	}
+
+	change := storeChange{jots: make(map[string]*Jot)}
+	for order, jot := range ordered {
+		if jot.PinOrder != order {
+			updated := jot.clone()
+			updated.PinOrder = order
+			change.jots[jot.ID] = updated
+		}
+	}
+	return s.writeLocked(change)
+}
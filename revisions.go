@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RevisionPolicy controls when revisions are taken and how long they are kept.
+type RevisionPolicy struct {
+	// CoalesceWindow is the time within which saves replace the latest
+	// revision instead of adding a new one.
+	CoalesceWindow time.Duration
+	// MaxAge is how long revisions are kept. The latest revision is always kept.
+	MaxAge time.Duration
+	// MaxRevisions is the maximum number of revisions kept per jot.
+	MaxRevisions int
+}
+
+// DefaultRevisionPolicy is the revision policy used by the application.
+var DefaultRevisionPolicy = RevisionPolicy{
+	CoalesceWindow: 5 * time.Minute,
+	MaxAge:         30 * 24 * time.Hour,
+	MaxRevisions:   100,
+}
+
+// RevisionInfo describes a revision without its content.
+type RevisionInfo struct {
+	ID         string    `json:"id"`
+	JotID      string    `json:"jotId"`
+	Title      string    `json:"title"`
+	CreatedAt  time.Time `json:"createdAt"`
+	TextLength int       `json:"textLength"`
+}
+
+// Revision is a snapshot of a jot at a point in time.
+type Revision struct {
+	ID          string    `json:"id"`
+	JotID       string    `json:"jotId"`
+	Title       string    `json:"title"`
+	Content     *Node     `json:"content"`
+	TextContent string    `json:"textContent"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// StartedAt is the time of the first save coalesced into the revision,
+	// from which the coalesce window is counted.
+	StartedAt time.Time `json:"startedAt,omitempty"`
+}
+
+// DiffLine is a single line of a diff between two revisions. Op is one of
+// "equal", "insert" or "delete".
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// RevisionStore keeps the revisions of every jot in a file per jot.
+type RevisionStore struct {
+	mu     sync.Mutex
+	dir    string
+	policy RevisionPolicy
+	// key seals revision files when the store is encrypted. previousKey can
+	// still open files sealed before a key change.
+	key         *noteKey
+	previousKey *noteKey
+}
+
+// NewRevisionStore creates a revision store in the revisions directory of
+// dataDir.
+func NewRevisionStore(dataDir string, policy RevisionPolicy) *RevisionStore {
+	return &RevisionStore{
+		dir:    filepath.Join(dataDir, "revisions"),
+		policy: policy,
+	}
+}
+
+// Record takes a revision of the jot if it differs from the latest revision.
+// When coalesce is set, saves within the coalesce window of the first save
+// of the latest revision replace it, unless the save removes most of the
+// text, so a large deletion can always be undone. Continuous editing still
+// leaves a revision per window.
+func (r *RevisionStore) Record(jot *Jot, coalesce bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revisions, err := r.load(jot.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	revision := &Revision{
+		ID:          strconv.FormatInt(now.UnixNano(), 10),
+		JotID:       jot.ID,
+		Title:       jot.Title,
+		Content:     jot.Content.Clone(),
+		TextContent: jot.TextContent,
+		CreatedAt:   now,
+		StartedAt:   now,
+	}
+
+	if len(revisions) > 0 {
+		latest := revisions[len(revisions)-1]
+		if sameRevisionContent(latest, revision) {
+			return nil
+		}
+		started := latest.StartedAt
+		if started.IsZero() {
+			started = latest.CreatedAt
+		}
+		if coalesce && now.Sub(started) < r.policy.CoalesceWindow && !isLargeDeletion(latest, revision) {
+			revisions = revisions[:len(revisions)-1]
+			revision.StartedAt = started
+		}
+	}
+	revisions = append(revisions, revision)
+
+	return r.save(jot.ID, r.applyRetention(revisions, now))
+}
+
+// List returns the revisions of a jot, newest first.
+func (r *RevisionStore) List(jotID string) ([]RevisionInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revisions, err := r.load(jotID)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]RevisionInfo, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		revision := revisions[i]
+		infos = append(infos, RevisionInfo{
+			ID:         revision.ID,
+			JotID:      revision.JotID,
+			Title:      revision.Title,
+			CreatedAt:  revision.CreatedAt,
+			TextLength: len(revision.TextContent),
+		})
+	}
+	return infos, nil
+}
+
+// Get returns a single revision of a jot.
+func (r *RevisionStore) Get(jotID, revisionID string) (*Revision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revisions, err := r.load(jotID)
+	if err != nil {
+		return nil, err
+	}
+	for _, revision := range revisions {
+		if revision.ID == revisionID {
+			return revision, nil
+		}
+	}
+	return nil, fmt.Errorf("revision %s of jot %s not found", revisionID, jotID)
+}
+
+// Remove deletes all revisions of a jot.
+func (r *RevisionStore) Remove(jotID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := os.Remove(r.path(jotID))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing revisions: %w", err)
+	}
+	return nil
+}
+
+// applyRetention drops revisions that are too old or over the limit. The
+// latest revision is always kept.
+func (r *RevisionStore) applyRetention(revisions []*Revision, now time.Time) []*Revision {
+	if r.policy.MaxAge > 0 {
+		kept := revisions[:0]
+		for i, revision := range revisions {
+			if i == len(revisions)-1 || now.Sub(revision.CreatedAt) <= r.policy.MaxAge {
+				kept = append(kept, revision)
+			}
+		}
+		revisions = kept
+	}
+	if r.policy.MaxRevisions > 0 && len(revisions) > r.policy.MaxRevisions {
+		revisions = revisions[len(revisions)-r.policy.MaxRevisions:]
+	}
+	return revisions
+}
+
+// SetKeys sets the key used to seal revision files, nil to write them in
+// plain text, and a previous key that can still open older files.
+func (r *RevisionStore) SetKeys(key, previousKey *noteKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.key, r.previousKey = key, previousKey
+}
+
+// Reseal rewrites every revision file with the current key, or in plain
+// text if there is none.
+func (r *RevisionStore) Reseal() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries, err := os.ReadDir(r.dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading revisions: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".json" {
+			continue
+		}
+		jotID := strings.TrimSuffix(name, ".json")
+		revisions, err := r.load(jotID)
+		if err != nil {
+			return err
+		}
+		if err := r.save(jotID, revisions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// path returns the revisions file of a jot.
+func (r *RevisionStore) path(jotID string) string {
+	return filepath.Join(r.dir, filepath.Base(jotID)+".json")
+}
+
+// load reads the revisions of a jot, oldest first.
+func (r *RevisionStore) load(jotID string) ([]*Revision, error) {
+	data, err := os.ReadFile(r.path(jotID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading revisions: %w", err)
+	}
+
+	// Plain revision files hold an array, sealed ones an envelope object.
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var box SealedBox
+		if err := json.Unmarshal(trimmed, &box); err != nil {
+			return nil, fmt.Errorf("error parsing revisions: %w", err)
+		}
+		var key *noteKey
+		for _, candidate := range []*noteKey{r.key, r.previousKey} {
+			if candidate != nil && candidate.id == box.KeyID {
+				key = candidate
+			}
+		}
+		if key == nil {
+			return nil, errStoreLocked
+		}
+		if data, err = key.open(&box, revisionsAdditionalData(jotID)); err != nil {
+			return nil, err
+		}
+	}
+
+	var revisions []*Revision
+	if err := json.Unmarshal(data, &revisions); err != nil {
+		return nil, fmt.Errorf("error parsing revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// save writes the revisions of a jot.
+func (r *RevisionStore) save(jotID string, revisions []*Revision) error {
+	data, err := json.Marshal(revisions)
+	if err != nil {
+		return fmt.Errorf("error encoding revisions: %w", err)
+	}
+	if r.key != nil {
+		box, err := r.key.seal(data, revisionsAdditionalData(jotID))
+		if err != nil {
+			return err
+		}
+		if data, err = json.Marshal(box); err != nil {
+			return fmt.Errorf("error encoding revisions: %w", err)
+		}
+	}
+	return writeFileAtomic(r.path(jotID), data)
+}
+
+// sameRevisionContent reports whether two revisions have the same title and
+// content.
+func sameRevisionContent(a, b *Revision) bool {
+	if a.Title != b.Title || a.TextContent != b.TextContent {
+		return false
+	}
+	aContent, errA := json.Marshal(a.Content)
+	bContent, errB := json.Marshal(b.Content)
+	return errA == nil && errB == nil && bytes.Equal(aContent, bContent)
+}
+
+// isLargeDeletion reports whether next removed more than half of the text of
+// previous.
+func isLargeDeletion(previous, next *Revision) bool {
+	return len(next.TextContent) < len(previous.TextContent)/2
+}
+
+// maxDiffCells caps the table of the line diff, about 16 MB. Changed parts
+// larger than that are shown as deleted and inserted as a whole.
+const maxDiffCells = 4 << 20
+
+// diffLines returns a line diff between a and b based on their longest
+// common subsequence. Lines the two share at the start and end are taken
+// out first, so the table only covers the part that changed.
+func diffLines(a, b []string) []DiffLine {
+	diff := []DiffLine{}
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		diff = append(diff, DiffLine{Op: "equal", Text: a[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	diff = append(diff, diffChanged(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		diff = append(diff, DiffLine{Op: "equal", Text: line})
+	}
+	return diff
+}
+
+// diffChanged diffs the changed part of two texts.
+func diffChanged(a, b []string) []DiffLine {
+	var diff []DiffLine
+	width := len(b) + 1
+	if len(a) > 0 && len(b) > 0 && (len(a)+1)*width <= maxDiffCells {
+		lcs := make([]int32, (len(a)+1)*width)
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+				} else {
+					lcs[i*width+j] = max(lcs[(i+1)*width+j], lcs[i*width+j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(a) && j < len(b) {
+			switch {
+			case a[i] == b[j]:
+				diff = append(diff, DiffLine{Op: "equal", Text: a[i]})
+				i++
+				j++
+			case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+				diff = append(diff, DiffLine{Op: "delete", Text: a[i]})
+				i++
+			default:
+				diff = append(diff, DiffLine{Op: "insert", Text: b[j]})
+				j++
+			}
+		}
+		a, b = a[i:], b[j:]
+	}
+	for _, line := range a {
+		diff = append(diff, DiffLine{Op: "delete", Text: line})
+	}
+	for _, line := range b {
+		diff = append(diff, DiffLine{Op: "insert", Text: line})
+	}
+	return diff
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The schema below mirrors the extensions of the editor in Editor.vue.
+// Documents that do not follow it may fail to render, so they are repaired
+// before they are stored.
+
+// blockNodeTypes are the nodes allowed where blocks are expected.
+var blockNodeTypes = map[string]bool{
+	"paragraph":      true,
+	"heading":        true,
+	"blockquote":     true,
+	"bulletList":     true,
+	"orderedList":    true,
+	"taskList":       true,
+	"horizontalRule": true,
+}
+
+// inlineNodeTypes are the nodes allowed inside paragraphs and headings.
+var inlineNodeTypes = map[string]bool{
+	"text":       true,
+	noteLinkType: true,
+}
+
+// markTypes are the marks allowed on inline nodes.
+var markTypes = map[string]bool{
+	"bold":   true,
+	"italic": true,
+}
+
+// maxReportedProblems is the number of problems described for one jot in
+// an import report.
+const maxReportedProblems = 5
+
+// SchemaProblem is a part of a document that did not follow the editor
+// schema, and what was done about it.
+type SchemaProblem struct {
+	// Path is the JSON pointer of the node in the original document.
+	Path    string `json:"path"`
+	Problem string `json:"problem"`
+}
+
+// String describes the problem for logs and reports.
+func (p SchemaProblem) String() string {
+	if p.Path == "" {
+		return p.Problem
+	}
+	return p.Problem + " at " + p.Path
+}
+
+// ValidateDocument returns the problems of a document. It returns an empty
+// list for a document the editor can render. Only documents that do not
+// follow the schema are rebuilt to find their problems.
+func ValidateDocument(doc *Node) []SchemaProblem {
+	if followsSchema(doc) {
+		return []SchemaProblem{}
+	}
+	_, problems := RepairDocument(doc)
+	return problems
+}
+
+// followsSchema reports whether a document follows the editor schema,
+// without copying it. It checks what RepairDocument would change.
+func followsSchema(doc *Node) bool {
+	return doc != nil && doc.Type == "doc" && validBlocks(doc.Content)
+}
+
+// validBlocks reports whether nodes are blocks that follow the schema.
+func validBlocks(nodes []*Node) bool {
+	for _, node := range nodes {
+		if !validBlock(node) {
+			return false
+		}
+	}
+	return true
+}
+
+// validBlock reports whether a node is a block that follows the schema.
+func validBlock(node *Node) bool {
+	if node == nil {
+		return false
+	}
+	switch node.Type {
+	case "paragraph":
+		return validInlines(node.Content)
+	case "heading":
+		level, ok := integerAttr(node.Attrs["level"])
+		return ok && level >= 1 && level <= 6 && validInlines(node.Content)
+	case "blockquote":
+		return len(node.Content) > 0 && validBlocks(node.Content)
+	case "bulletList", "orderedList", "taskList":
+		return validList(node)
+	case "horizontalRule":
+		return len(node.Content) == 0
+	}
+	return false
+}
+
+// validList reports whether a list holds only items of its kind that
+// follow the schema.
+func validList(node *Node) bool {
+	if len(node.Content) == 0 {
+		return false
+	}
+	if value, present := node.Attrs["start"]; present && node.Type == "orderedList" {
+		if start, ok := integerAttr(value); !ok || start < 0 {
+			return false
+		}
+	}
+	itemType := "listItem"
+	if node.Type == "taskList" {
+		itemType = "taskItem"
+	}
+	for _, item := range node.Content {
+		if item == nil || item.Type != itemType || len(item.Content) == 0 || item.Content[0] == nil || item.Content[0].Type != "paragraph" || !validBlocks(item.Content) {
+			return false
+		}
+		if _, ok := item.Attrs["checked"].(bool); itemType == "taskItem" && !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// validInlines reports whether nodes are inline nodes that follow the
+// schema.
+func validInlines(nodes []*Node) bool {
+	for _, node := range nodes {
+		if node == nil || !validMarks(node.Marks) {
+			return false
+		}
+		switch node.Type {
+		case "text":
+			if node.Text == "" || len(node.Content) > 0 {
+				return false
+			}
+		case noteLinkType:
+			if node.StringAttr("jotId") == "" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validMarks reports whether marks are known and not repeated.
+func validMarks(marks []Mark) bool {
+	for i, mark := range marks {
+		if !markTypes[mark.Type] {
+			return false
+		}
+		for _, earlier := range marks[:i] {
+			if earlier.Type == mark.Type {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// RepairDocument returns a copy of a document changed to follow the editor
+// schema, and the problems that were fixed. Inline content outside of a
+// paragraph is wrapped in one, blocks inside paragraphs are replaced by
+// their text, list content that is not an item is put into one, unknown
+// nodes are replaced by their content or text, and unknown marks, empty
+// text nodes, empty lists and note links without a target are removed.
+// Invalid heading levels, list starts and checkbox states are reset. An
+// empty document is valid, as the editor fills it with a paragraph.
+func RepairDocument(doc *Node) (*Node, []SchemaProblem) {
+	r := &schemaRepairer{problems: []SchemaProblem{}}
+	repaired := NewDocument()
+	switch {
+	case doc == nil:
+		r.report("", "missing document replaced with an empty one")
+	case doc.Type != "doc":
+		r.report("", "root %s wrapped in a document", describeNodeType(doc.Type))
+		repaired.Content = r.block("", doc)
+	default:
+		repaired.Content = r.blocks("", doc.Content)
+	}
+	return repaired, r.problems
+}
+
+// describeProblems summarizes problems for a report, listing the first few.
+func describeProblems(problems []SchemaProblem) string {
+	descriptions := make([]string, 0, maxReportedProblems+1)
+	for i, problem := range problems {
+		if i == maxReportedProblems {
+			descriptions = append(descriptions, fmt.Sprintf("and %d more", len(problems)-i))
+			break
+		}
+		descriptions = append(descriptions, problem.String())
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+// schemaRepairer rebuilds a document and records what it changed.
+type schemaRepairer struct {
+	problems []SchemaProblem
+}
+
+// report records a problem at path.
+func (r *schemaRepairer) report(path, problem string, args ...interface{}) {
+	r.problems = append(r.problems, SchemaProblem{Path: path, Problem: fmt.Sprintf(problem, args...)})
+}
+
+// childPath returns the path of the i-th child of the node at path.
+func childPath(path string, i int) string {
+	return path + "/content/" + strconv.Itoa(i)
+}
+
+// describeNodeType names a node type in a problem.
+func describeNodeType(nodeType string) string {
+	if nodeType == "" {
+		return "node without a type"
+	}
+	return strconv.Quote(nodeType)
+}
+
+// blocks repairs a list of blocks. Runs of inline nodes are wrapped in a
+// paragraph.
+func (r *schemaRepairer) blocks(path string, nodes []*Node) []*Node {
+	var blocks []*Node
+	var run []*Node
+	for i, node := range nodes {
+		nodePath := childPath(path, i)
+		if node != nil && inlineNodeTypes[node.Type] {
+			if run == nil {
+				r.report(nodePath, "inline content outside of a paragraph wrapped in one")
+				run = []*Node{}
+			}
+			run = append(run, r.inline(nodePath, node)...)
+			continue
+		}
+		if run != nil {
+			blocks = append(blocks, &Node{Type: "paragraph", Content: run})
+			run = nil
+		}
+		blocks = append(blocks, r.block(nodePath, node)...)
+	}
+	if run != nil {
+		blocks = append(blocks, &Node{Type: "paragraph", Content: run})
+	}
+	return blocks
+}
+
+// block repairs a node where a block is expected. It may return no blocks
+// or several.
+func (r *schemaRepairer) block(path string, node *Node) []*Node {
+	if node == nil {
+		r.report(path, "empty node removed")
+		return nil
+	}
+	if inlineNodeTypes[node.Type] {
+		r.report(path, "inline content outside of a paragraph wrapped in one")
+		return []*Node{{Type: "paragraph", Content: r.inline(path, node)}}
+	}
+
+	switch node.Type {
+	case "paragraph":
+		return []*Node{{Type: node.Type, Attrs: copyAttrs(node.Attrs), Content: r.inlines(path, node.Content)}}
+	case "heading":
+		attrs := copyAttrs(node.Attrs)
+		if attrs == nil {
+			attrs = make(map[string]interface{}, 1)
+		}
+		level, ok := integerAttr(node.Attrs["level"])
+		if !ok || level < 1 || level > 6 {
+			fixed := min(max(level, 1), 6)
+			r.report(path, "heading level %#v replaced with %d", node.Attrs["level"], fixed)
+			level = fixed
+		}
+		attrs["level"] = level
+		return []*Node{{Type: node.Type, Attrs: attrs, Content: r.inlines(path, node.Content)}}
+	case "blockquote":
+		content := r.blocks(path, node.Content)
+		if len(content) == 0 {
+			r.report(path, "empty blockquote removed")
+			return nil
+		}
+		return []*Node{{Type: node.Type, Attrs: copyAttrs(node.Attrs), Content: content}}
+	case "bulletList", "orderedList", "taskList":
+		return r.list(path, node)
+	case "horizontalRule":
+		rule := []*Node{{Type: node.Type, Attrs: copyAttrs(node.Attrs)}}
+		if len(node.Content) > 0 {
+			r.report(path, "content of a horizontal rule moved after it")
+			return append(rule, r.blocks(path, node.Content)...)
+		}
+		return rule
+	case "listItem", "taskItem":
+		r.report(path, "%s outside of a list wrapped in one", describeNodeType(node.Type))
+		return r.list(path, &Node{Type: map[string]string{"listItem": "bulletList", "taskItem": "taskList"}[node.Type], Content: []*Node{node}})
+	}
+
+	switch {
+	case len(node.Content) > 0:
+		r.report(path, "unknown node %s replaced with its content", describeNodeType(node.Type))
+		return r.blocks(path, node.Content)
+	case node.Text != "":
+		r.report(path, "unknown node %s replaced with its text", describeNodeType(node.Type))
+		return []*Node{{Type: "paragraph", Content: []*Node{{Type: "text", Text: node.Text}}}}
+	}
+	r.report(path, "unknown node %s removed", describeNodeType(node.Type))
+	return nil
+}
+
+// list repairs a bullet, ordered or task list. Items of the other kind of
+// list are converted, other content is put into an item of its own, and a
+// nested list without an item joins the item before it.
+func (r *schemaRepairer) list(path string, node *Node) []*Node {
+	itemType := "listItem"
+	if node.Type == "taskList" {
+		itemType = "taskItem"
+	}
+
+	var items []*Node
+	for i, child := range node.Content {
+		itemPath := childPath(path, i)
+		switch {
+		case child == nil:
+			r.report(itemPath, "empty node removed")
+		case child.Type == itemType:
+			items = append(items, r.item(itemPath, child, itemType))
+		case child.Type == "listItem" || child.Type == "taskItem":
+			r.report(itemPath, "%s in a %s changed to %s", child.Type, node.Type, itemType)
+			items = append(items, r.item(itemPath, child, itemType))
+		default:
+			content := r.block(itemPath, child)
+			if len(content) == 0 {
+				continue
+			}
+			isList := content[0].Type == "bulletList" || content[0].Type == "orderedList" || content[0].Type == "taskList"
+			if isList && len(items) > 0 {
+				r.report(itemPath, "list directly inside a list moved into the item before it")
+				last := items[len(items)-1]
+				last.Content = append(last.Content, content...)
+				continue
+			}
+			r.report(itemPath, "%s in a %s wrapped in a list item", describeNodeType(child.Type), node.Type)
+			items = append(items, r.item(itemPath, &Node{Type: itemType, Content: content}, itemType))
+		}
+	}
+	if len(items) == 0 {
+		r.report(path, "empty %s removed", node.Type)
+		return nil
+	}
+
+	list := &Node{Type: node.Type, Attrs: copyAttrs(node.Attrs), Content: items}
+	if node.Type == "orderedList" && node.Attrs != nil {
+		if value, present := node.Attrs["start"]; present {
+			if start, ok := integerAttr(value); !ok || start < 0 {
+				r.report(path, "list start %#v replaced with 1", value)
+				list.Attrs["start"] = 1
+			}
+		}
+	}
+	return []*Node{list}
+}
+
+// item repairs a list or task item, which must start with a paragraph.
+// Content that was already repaired by list is repaired again, which
+// changes nothing.
+func (r *schemaRepairer) item(path string, node *Node, itemType string) *Node {
+	content := r.blocks(path, node.Content)
+	if len(content) == 0 || content[0].Type != "paragraph" {
+		r.report(path, "%s without a leading paragraph given one", itemType)
+		content = withParagraph(content)
+	}
+
+	item := &Node{Type: itemType, Attrs: copyAttrs(node.Attrs), Content: content}
+	if itemType == "listItem" {
+		delete(item.Attrs, "checked")
+		if len(item.Attrs) == 0 {
+			item.Attrs = nil
+		}
+		return item
+	}
+	if item.Attrs == nil {
+		item.Attrs = make(map[string]interface{}, 1)
+	}
+	checked, ok := item.Attrs["checked"].(bool)
+	if !ok {
+		if value, present := item.Attrs["checked"]; present {
+			checked = value == "true"
+			r.report(path, "checkbox state %#v replaced with %t", value, checked)
+		} else if node.Type == "taskItem" {
+			r.report(path, "task item without a checkbox state unchecked")
+		}
+	}
+	item.Attrs["checked"] = checked
+	return item
+}
+
+// inlines repairs the content of a paragraph or heading.
+func (r *schemaRepairer) inlines(path string, nodes []*Node) []*Node {
+	var content []*Node
+	for i, node := range nodes {
+		content = append(content, r.inline(childPath(path, i), node)...)
+	}
+	return content
+}
+
+// inline repairs a node inside a paragraph or heading. Other nodes are
+// replaced by the text and note links inside them.
+func (r *schemaRepairer) inline(path string, node *Node) []*Node {
+	if node == nil {
+		r.report(path, "empty node removed")
+		return nil
+	}
+
+	switch node.Type {
+	case "text":
+		if node.Text == "" {
+			r.report(path, "empty text node removed")
+			return nil
+		}
+		if len(node.Content) > 0 {
+			r.report(path, "content of a text node removed")
+		}
+		return []*Node{{Type: "text", Text: node.Text, Marks: r.marks(path, node.Marks)}}
+	case noteLinkType:
+		jotID := node.StringAttr("jotId")
+		if jotID != "" {
+			return []*Node{{Type: noteLinkType, Attrs: copyAttrs(node.Attrs), Marks: r.marks(path, node.Marks)}}
+		}
+		if label := node.StringAttr("label"); label != "" {
+			r.report(path, "note link without a target replaced with its label")
+			return []*Node{{Type: "text", Text: label, Marks: r.marks(path, node.Marks)}}
+		}
+		r.report(path, "note link without a target removed")
+		return nil
+	case "hardBreak":
+		r.report(path, "line break replaced with a space")
+		return []*Node{{Type: "text", Text: " "}}
+	}
+
+	if blockNodeTypes[node.Type] {
+		r.report(path, "%s inside a paragraph replaced with its text", node.Type)
+	} else {
+		r.report(path, "unknown node %s replaced with its text", describeNodeType(node.Type))
+	}
+	var content []*Node
+	if node.Text != "" {
+		content = append(content, &Node{Type: "text", Text: node.Text})
+	}
+	node.Walk(func(descendant *Node) bool {
+		switch {
+		case descendant == node:
+		case descendant.Type == "text" && descendant.Text != "":
+			content = append(content, &Node{Type: "text", Text: descendant.Text, Marks: r.marks(path, descendant.Marks)})
+		case descendant.Type == noteLinkType && descendant.StringAttr("jotId") != "":
+			content = append(content, &Node{Type: noteLinkType, Attrs: copyAttrs(descendant.Attrs)})
+		}
+		return true
+	})
+	return content
+}
+
+// marks repairs the marks of an inline node, dropping unknown and repeated
+// ones.
+func (r *schemaRepairer) marks(path string, marks []Mark) []Mark {
+	if len(marks) == 0 {
+		return nil
+	}
+	var repaired []Mark
+	seen := make(map[string]bool, len(marks))
+	for _, mark := range marks {
+		switch {
+		case !markTypes[mark.Type]:
+			r.report(path, "unknown mark %s removed", describeNodeType(mark.Type))
+		case seen[mark.Type]:
+			r.report(path, "repeated %s mark removed", mark.Type)
+		default:
+			seen[mark.Type] = true
+			repaired = append(repaired, Mark{Type: mark.Type, Attrs: copyAttrs(mark.Attrs)})
+		}
+	}
+	return repaired
+}
+
+// copyAttrs returns a copy of node attributes, or nil if there are none.
+func copyAttrs(attrs map[string]interface{}) map[string]interface{} {
+	if len(attrs) == 0 {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(attrs))
+	for key, value := range attrs {
+		copied[key] = value
+	}
+	return copied
+}
+
+// integerAttr returns an attribute holding a whole number as an int.
+func integerAttr(value interface{}) (int, bool) {
+	switch number := value.(type) {
+	case int:
+		return number, true
+	case float64:
+		if number == math.Trunc(number) && math.Abs(number) < math.MaxInt32 {
+			return int(number), true
+		}
+	}
+	return 0, false
+}
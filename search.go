@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// snippetRadius is the number of characters shown on each side of the
+// first match in a search snippet.
+const snippetRadius = 60
+
+// SearchResult is a jot matching a search, with the text around the first
+// match.
+type SearchResult struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Snippet   string    `json:"snippet"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Search returns the jots whose title or text contain every word of query,
+// ignoring case. Matches in the title rank above matches in the text, and
+// equal matches are sorted by updatedAt descending. A limit of 0 or less
+// returns all matches.
+func (s *Store) Search(query string, limit int) []SearchResult {
+	terms := strings.Fields(strings.ToLower(query))
+	results := []SearchResult{}
+	if len(terms) == 0 {
+		return results
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	scores := make(map[string]int)
+	for _, jot := range s.sortedLocked() {
+		title := strings.ToLower(jot.Title)
+		text := strings.ToLower(jot.TextContent)
+		score := 0
+		for _, term := range terms {
+			inTitle := strings.Contains(title, term)
+			count := strings.Count(text, term)
+			if !inTitle && count == 0 {
+				score = 0
+				break
+			}
+			if inTitle {
+				score += 10
+			}
+			score += min(count, 5)
+		}
+		if score == 0 {
+			continue
+		}
+		scores[jot.ID] = score
+		results = append(results, SearchResult{
+			ID:        jot.ID,
+			Title:     jot.Title,
+			Snippet:   searchSnippet(jot.TextContent, terms),
+			UpdatedAt: jot.UpdatedAt,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return scores[results[i].ID] > scores[results[j].ID]
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// searchSnippet returns the text around the first term found in text, with
+// line breaks replaced by spaces, or the start of the text if no term is in
+// it.
+func searchSnippet(text string, terms []string) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		// Lower-casing changed the length, so positions do not line up.
+		lower = runes
+	}
+
+	position := -1
+	for _, term := range terms {
+		if index := strings.Index(string(lower), term); index >= 0 {
+			position = len([]rune(string(lower)[:index]))
+			break
+		}
+	}
+	start, end := 0, min(len(runes), 2*snippetRadius)
+	if position >= 0 {
+		start = max(position-snippetRadius, 0)
+		end = min(position+snippetRadius, len(runes))
+	}
+
+	snippet := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Settings are the user preferences that are kept on the Go side.
+type Settings struct {
+	// TrashRetentionDays is the number of days jots stay in the trash before
+	// they are purged. Zero keeps them until the trash is emptied.
+	TrashRetentionDays int `json:"trashRetentionDays"`
+
+	// BackupDirectory is where automatic backups are written. Automatic
+	// backups are off while it is empty.
+	BackupDirectory string `json:"backupDirectory"`
+	// BackupIntervalHours is the time between automatic backups. Zero turns
+	// them off.
+	BackupIntervalHours int `json:"backupIntervalHours"`
+	// BackupKeep is the number of automatic backups kept in the directory.
+	// Zero keeps all of them.
+	BackupKeep int `json:"backupKeep"`
+
+	// AutoLockMinutes locks an encrypted store after this many minutes
+	// without activity. Zero turns auto-lock off.
+	AutoLockMinutes int `json:"autoLockMinutes"`
+
+	// APIEnabled turns on the local HTTP API, which listens on 127.0.0.1 at
+	// APIPort.
+	APIEnabled bool `json:"apiEnabled"`
+	APIPort    int  `json:"apiPort"`
+
+	// InboxJotID is the jot quick captures are appended to. The first
+	// capture creates it.
+	InboxJotID string `json:"inboxJotId"`
+
+	// DailyTemplate is the Markdown a new daily note starts with.
+	// DailyTitlePattern formats its title, see FormatDailyTitle.
+	DailyTemplate     string `json:"dailyTemplate"`
+	DailyTitlePattern string `json:"dailyTitlePattern"`
+}
+
+// DefaultSettings are used for any setting that has not been saved yet.
+var DefaultSettings = Settings{
+	TrashRetentionDays:  30,
+	BackupIntervalHours: 24,
+	BackupKeep:          10,
+	AutoLockMinutes:     15,
+	APIPort:             27183,
+	DailyTitlePattern:   defaultDailyTitlePattern,
+}
+
+// SettingsStore loads and saves the settings file.
+type SettingsStore struct {
+	mu       sync.RWMutex
+	path     string
+	settings Settings
+}
+
+// NewSettingsStore creates a settings store backed by the settings file in
+// dataDir.
+func NewSettingsStore(dataDir string) *SettingsStore {
+	return &SettingsStore{
+		path:     filepath.Join(dataDir, "settings.json"),
+		settings: DefaultSettings,
+	}
+}
+
+// Load reads the settings file. Missing settings keep their defaults.
+func (s *SettingsStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading settings: %w", err)
+	}
+
+	settings := DefaultSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return fmt.Errorf("error parsing settings: %w", err)
+	}
+	s.settings = settings
+	return nil
+}
+
+// Get returns the current settings.
+func (s *SettingsStore) Get() Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.settings
+}
+
+// Update validates and saves new settings.
+func (s *SettingsStore) Update(settings Settings) error {
+	if settings.TrashRetentionDays < 0 {
+		return fmt.Errorf("trash retention must not be negative")
+	}
+	if settings.BackupIntervalHours < 0 {
+		return fmt.Errorf("backup interval must not be negative")
+	}
+	if settings.BackupKeep < 0 {
+		return fmt.Errorf("number of backups to keep must not be negative")
+	}
+	if settings.AutoLockMinutes < 0 {
+		return fmt.Errorf("auto-lock time must not be negative")
+	}
+	if settings.APIPort < 1024 || settings.APIPort > 65535 {
+		return fmt.Errorf("API port must be between 1024 and 65535")
+	}
+	if len(settings.DailyTemplate) > maxImportFileSize {
+		return fmt.Errorf("daily note template is larger than %d MB", maxImportFileSize>>20)
+	}
+	if settings.BackupDirectory != "" && !filepath.IsAbs(settings.BackupDirectory) {
+		return fmt.Errorf("backup directory must be an absolute path")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding settings: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return err
+	}
+	s.settings = settings
+	return nil
+}
@@ -0,0 +1,703 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// storeSchemaVersion is the version of the on-disk store format.
+const storeSchemaVersion = 1
+
+// Jot is a single note as stored by the Go side of the application.
+type Jot struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Content     *Node     `json:"content"`
+	TextContent string    `json:"textContent"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	// FolderID is the folder the jot is in, empty for the root.
+	FolderID string `json:"folderId,omitempty"`
+	// Position is the place of the jot in a manually sorted folder.
+	Position int `json:"position,omitempty"`
+	// Pinned jots are listed first, in PinOrder.
+	Pinned   bool `json:"pinned,omitempty"`
+	PinOrder int  `json:"pinOrder,omitempty"`
+	// Favourite marks a jot for the favourites list.
+	Favourite bool `json:"favourite,omitempty"`
+	// Lock holds the encrypted content of a jot locked with its own
+	// password. Content and TextContent are empty while it is set.
+	Lock *JotLock `json:"lock,omitempty"`
+	// DeletedAt is set while the jot is in the trash.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// Source is the web page a clipped jot came from.
+	Source *JotSource `json:"source,omitempty"`
+	// DailyDate is the date, as YYYY-MM-DD, of a daily note.
+	DailyDate string `json:"dailyDate,omitempty"`
+}
+
+// storeFile is the layout of the store file on disk.
+type storeFile struct {
+	Version int       `json:"version"`
+	Jots    []*Jot    `json:"jots"`
+	Trash   []*Jot    `json:"trash,omitempty"`
+	Folders []*Folder `json:"folders,omitempty"`
+	// Migrations are the markers of one-time data migrations. They hold no
+	// content and stay readable when the store is encrypted.
+	Migrations map[string]*Migration `json:"migrations,omitempty"`
+	// Encryption and Sealed replace the fields above when the store is
+	// encrypted.
+	Encryption *encryptionHeader `json:"encryption,omitempty"`
+	Sealed     *sealedStore      `json:"sealed,omitempty"`
+}
+
+// Store keeps all jots in memory and persists them to a single JSON file.
+// Every write replaces the file atomically, so a set of changes made under
+// one lock is either fully on disk or not at all.
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	jots    map[string]*Jot
+	trash   map[string]*Jot
+	folders map[string]*Folder
+	// migrations are the markers of one-time data migrations.
+	migrations map[string]*Migration
+	links      *LinkIndex
+	tags       *TagIndex
+	revisions  *RevisionStore
+	// encryption is set when the store is encrypted. key is the data key
+	// while the store is unlocked.
+	encryption *encryptionHeader
+	key        *noteKey
+	locked     bool
+	// jotKeys are the keys of locked jots unlocked for this session.
+	jotKeys map[string]*noteKey
+	// dailyMu keeps two callers from creating the daily note of the same
+	// date.
+	dailyMu sync.Mutex
+	// keyMu serializes enabling and disabling encryption, password changes,
+	// unlocking and locking, which derive keys and reseal revisions without
+	// holding mu.
+	keyMu sync.Mutex
+}
+
+// DefaultDataDir returns the directory the application keeps its data in.
+func DefaultDataDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding config directory: %w", err)
+	}
+	return filepath.Join(configDir, "toJot"), nil
+}
+
+// NewStore creates a store backed by the jots file in dataDir.
+func NewStore(dataDir string) *Store {
+	return &Store{
+		path:       filepath.Join(dataDir, "jots.json"),
+		jots:       make(map[string]*Jot),
+		trash:      make(map[string]*Jot),
+		folders:    make(map[string]*Folder),
+		migrations: make(map[string]*Migration),
+		links:      NewLinkIndex(),
+		tags:       NewTagIndex(),
+		revisions:  NewRevisionStore(dataDir, DefaultRevisionPolicy),
+		jotKeys:    make(map[string]*noteKey),
+	}
+}
+
+// Load reads the store file from disk. A missing file is an empty store. An
+// encrypted store stays locked and empty until it is unlocked.
+func (s *Store) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.readFile()
+	if err != nil {
+		return err
+	}
+	if file.Encryption != nil {
+		s.encryption, s.key, s.locked = file.Encryption, nil, true
+		s.loadLocked(storeFile{})
+		return nil
+	}
+	s.loadLocked(file)
+	return nil
+}
+
+// readFile reads and parses the store file.
+func (s *Store) readFile() (storeFile, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return storeFile{}, nil
+	}
+	if err != nil {
+		return storeFile{}, fmt.Errorf("error reading store: %w", err)
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return storeFile{}, fmt.Errorf("error parsing store: %w", err)
+	}
+	if file.Version > storeSchemaVersion {
+		return storeFile{}, fmt.Errorf("store version %d is newer than supported version %d", file.Version, storeSchemaVersion)
+	}
+	return file, nil
+}
+
+// loadLocked replaces the contents of the store and rebuilds the indexes.
+// The caller must hold the write lock.
+func (s *Store) loadLocked(file storeFile) {
+	s.jots = make(map[string]*Jot, len(file.Jots))
+	s.links = NewLinkIndex()
+	s.tags = NewTagIndex()
+	for _, jot := range file.Jots {
+		s.jots[jot.ID] = jot
+		s.indexLocked(jot.ID, jot)
+	}
+	s.trash = make(map[string]*Jot, len(file.Trash))
+	for _, jot := range file.Trash {
+		s.trash[jot.ID] = jot
+	}
+	s.folders = make(map[string]*Folder, len(file.Folders))
+	for _, folder := range file.Folders {
+		s.folders[folder.ID] = folder
+	}
+	s.migrations = file.Migrations
+	if s.migrations == nil {
+		s.migrations = make(map[string]*Migration)
+	}
+}
+
+// persist writes the current state to disk. The caller must hold the lock.
+func (s *Store) persist() error {
+	file := storeFile{
+		Version: storeSchemaVersion,
+		Jots:    sortJots(s.jots),
+		Trash:   sortJots(s.trash),
+		Folders: make([]*Folder, 0, len(s.folders)),
+	}
+	if len(s.migrations) > 0 {
+		file.Migrations = s.migrations
+	}
+	for _, folder := range s.folders {
+		file.Folders = append(file.Folders, folder)
+	}
+	sort.Slice(file.Folders, func(i, j int) bool {
+		return file.Folders[i].ID < file.Folders[j].ID
+	})
+	if s.encryption != nil {
+		if s.key == nil {
+			return errStoreLocked
+		}
+		file.Encryption = s.encryption
+		if err := sealStoreFile(&file, s.key); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("error encoding store: %w", err)
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// sortedLocked returns all jots sorted by updatedAt descending. The caller
+// must hold the lock.
+func (s *Store) sortedLocked() []*Jot {
+	return sortJots(s.jots)
+}
+
+// sortJots returns the jots of a map sorted by updatedAt descending.
+func sortJots(set map[string]*Jot) []*Jot {
+	jots := make([]*Jot, 0, len(set))
+	for _, jot := range set {
+		jots = append(jots, jot)
+	}
+	sort.Slice(jots, func(i, j int) bool {
+		if jots[i].UpdatedAt.Equal(jots[j].UpdatedAt) {
+			return jots[i].ID < jots[j].ID
+		}
+		return jots[i].UpdatedAt.After(jots[j].UpdatedAt)
+	})
+	return jots
+}
+
+// Get returns a copy of the jot with the given id.
+func (s *Store) Get(id string) (*Jot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jot, ok := s.jots[id]
+	if !ok {
+		return nil, false
+	}
+	return jot.clone(), true
+}
+
+// List returns copies of all jots sorted by updatedAt descending.
+func (s *Store) List() []*Jot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jots := s.sortedLocked()
+	for i, jot := range jots {
+		jots[i] = jot.clone()
+	}
+	return jots
+}
+
+// Save inserts or replaces a jot and updates the indexes. The folder,
+// position and pin and favourite flags of an existing jot are kept; they only
+// change through their own methods. Link labels in the jot are refreshed from
+// the titles of the jots they point at, and if the title changed, the labels
+// of every link pointing at this jot are rewritten in the same write. A
+// revision of the saved jot is recorded, coalescing rapid autosaves.
+func (s *Store) Save(jot *Jot) (*Jot, error) {
+	result, err := s.save(jot, true)
+	if err != nil {
+		return nil, err
+	}
+	return result.Jot, nil
+}
+
+// SaveResult is a saved jot and the other jots the save changed.
+type SaveResult struct {
+	Jot *Jot
+	// Relabelled are the jots whose links to the saved jot were relabelled
+	// with its new title.
+	Relabelled []*Jot
+	// Repairs are the changes made to the content of the jot to follow the
+	// editor schema.
+	Repairs []SchemaProblem
+}
+
+// SaveJot stores a jot like Save, and also returns the other jots that
+// changed with it.
+func (s *Store) SaveJot(jot *Jot) (*SaveResult, error) {
+	return s.save(jot, true)
+}
+
+// save stores a jot and records a revision of it.
+func (s *Store) save(jot *Jot, coalesce bool) (*SaveResult, error) {
+	if jot == nil || jot.ID == "" {
+		return nil, fmt.Errorf("jot has no id")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := jot.clone()
+	var repairs []SchemaProblem
+	if saved.Content == nil {
+		saved.Content = NewDocument()
+	} else if !followsSchema(saved.Content) {
+		saved.Content, repairs = RepairDocument(saved.Content)
+	}
+	now := time.Now()
+	previous, exists := s.jots[saved.ID]
+	// Autosaves can arrive out of order. One older than the stored jot is
+	// ignored, so it cannot overwrite newer content.
+	if exists && !saved.UpdatedAt.IsZero() && saved.UpdatedAt.Before(previous.UpdatedAt) {
+		return &SaveResult{Jot: previous.clone()}, nil
+	}
+	if exists {
+		saved.FolderID = previous.FolderID
+		saved.Position = previous.Position
+		saved.Pinned = previous.Pinned
+		saved.PinOrder = previous.PinOrder
+		saved.Favourite = previous.Favourite
+		if saved.DailyDate == "" {
+			saved.DailyDate = previous.DailyDate
+		}
+		if saved.Source == nil && previous.Source != nil {
+			source := *previous.Source
+			saved.Source = &source
+		}
+		if saved.CreatedAt.IsZero() {
+			saved.CreatedAt = previous.CreatedAt
+		}
+	} else if _, ok := s.folders[saved.FolderID]; !ok {
+		saved.FolderID = ""
+	}
+	if saved.CreatedAt.IsZero() {
+		saved.CreatedAt = now
+	}
+	if saved.UpdatedAt.IsZero() {
+		saved.UpdatedAt = now
+	}
+
+	changes := map[string]*Jot{saved.ID: saved}
+	// Links to the jot itself show the title it is saved with.
+	relabelNoteLinks(saved.Content, func(id string) (string, bool) {
+		if id == saved.ID {
+			return saved.Title, true
+		}
+		return s.titleLocked(id)
+	})
+	refreshText(saved)
+	if err := s.sealJotLocked(saved); err != nil {
+		return nil, err
+	}
+	if !exists || previous.Title != saved.Title {
+		for _, sourceID := range s.links.Backlinks(saved.ID) {
+			if sourceID == saved.ID {
+				continue
+			}
+			source := s.jots[sourceID].clone()
+			if relabelNoteLinks(source.Content, func(id string) (string, bool) {
+				return saved.Title, id == saved.ID
+			}) > 0 {
+				changes[sourceID] = source
+			}
+		}
+	}
+
+	change := storeChange{jots: changes}
+	// Saving a jot that is in the trash brings it back.
+	if _, ok := s.trash[saved.ID]; ok {
+		change.trash = map[string]*Jot{saved.ID: nil}
+	}
+
+	if err := s.writeLocked(change); err != nil {
+		return nil, err
+	}
+	// The revisions of a locked jot would hold its plain content, so none
+	// are kept.
+	if saved.Lock == nil {
+		if err := s.revisions.Record(saved, coalesce); err != nil {
+			fmt.Printf("Error recording revision of %s: %v\n", saved.ID, err)
+		}
+	}
+	result := &SaveResult{Jot: saved.clone(), Repairs: repairs}
+	for id := range changes {
+		if id != saved.ID {
+			result.Relabelled = append(result.Relabelled, s.jots[id].clone())
+		}
+	}
+	return result, nil
+}
+
+// Append adds blocks to the end of a jot and records a revision.
+func (s *Store) Append(id string, blocks []*Node) (*Jot, error) {
+	s.mu.RLock()
+	jot, ok := s.jots[id]
+	if ok {
+		jot = jot.clone()
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("jot %s not found", id)
+	}
+	if jot.Lock != nil {
+		return nil, errJotLocked
+	}
+
+	if jot.Content == nil {
+		jot.Content = NewDocument()
+	}
+	jot.Content.Content = append(jot.Content.Content, blocks...)
+	jot.UpdatedAt = time.Now()
+	result, err := s.save(jot, false)
+	if err != nil {
+		return nil, err
+	}
+	return result.Jot, nil
+}
+
+// Delete moves a jot to the trash and returns the links in other jots that
+// now point at nothing. Deleting an unknown id is not an error.
+func (s *Store) Delete(id string) ([]BrokenLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jot, ok := s.jots[id]
+	if !ok {
+		return []BrokenLink{}, nil
+	}
+
+	trashed := jot.clone()
+	now := time.Now()
+	trashed.DeletedAt = &now
+	if err := s.writeLocked(storeChange{
+		jots:  map[string]*Jot{id: nil},
+		trash: map[string]*Jot{id: trashed},
+	}); err != nil {
+		return nil, err
+	}
+	return s.brokenLinksLocked(id), nil
+}
+
+// storeChange is a set of modifications that are written to disk together.
+// A nil value deletes the entry with that id.
+type storeChange struct {
+	jots       map[string]*Jot
+	trash      map[string]*Jot
+	folders    map[string]*Folder
+	migrations map[string]*Migration
+}
+
+// writeLocked applies a change as one write. The text of the written jots
+// is recomputed from their content. If the write fails, the in-memory state
+// is rolled back. The caller must hold the write lock.
+func (s *Store) writeLocked(change storeChange) error {
+	if s.locked {
+		return errStoreLocked
+	}
+	for _, jot := range change.jots {
+		refreshText(jot)
+	}
+	for _, jot := range change.trash {
+		refreshText(jot)
+	}
+
+	undoJots := applyChanges(s.jots, change.jots)
+	undoTrash := applyChanges(s.trash, change.trash)
+	undoFolders := applyChanges(s.folders, change.folders)
+	undoMigrations := applyChanges(s.migrations, change.migrations)
+
+	if err := s.persist(); err != nil {
+		applyChanges(s.jots, undoJots)
+		applyChanges(s.trash, undoTrash)
+		applyChanges(s.folders, undoFolders)
+		applyChanges(s.migrations, undoMigrations)
+		return err
+	}
+
+	for id, jot := range change.jots {
+		s.indexLocked(id, jot)
+	}
+	return nil
+}
+
+// applyChanges applies changes to set and returns the changes that undo
+// them. A nil value deletes the entry.
+func applyChanges[T any](set map[string]*T, changes map[string]*T) map[string]*T {
+	undo := make(map[string]*T, len(changes))
+	for id, value := range changes {
+		undo[id] = set[id]
+		if value == nil {
+			delete(set, id)
+		} else {
+			set[id] = value
+		}
+	}
+	return undo
+}
+
+// indexLocked updates the indexes for a jot, or removes it from them if jot
+// is nil.
+func (s *Store) indexLocked(id string, jot *Jot) {
+	if jot == nil {
+		s.links.Remove(id)
+		s.tags.Remove(id)
+		return
+	}
+	s.links.Update(jot)
+	s.tags.Update(jot)
+}
+
+// titleLocked returns the title of a jot, for link relabeling.
+func (s *Store) titleLocked(id string) (string, bool) {
+	jot, ok := s.jots[id]
+	if !ok {
+		return "", false
+	}
+	return jot.Title, true
+}
+
+// Backlinks returns the jots that link to the given jot.
+func (s *Store) Backlinks(id string) []*Jot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jots := []*Jot{}
+	for _, sourceID := range s.links.Backlinks(id) {
+		if jot, ok := s.jots[sourceID]; ok {
+			jots = append(jots, jot.clone())
+		}
+	}
+	return jots
+}
+
+// BrokenLinks returns every link that points at a jot which does not exist.
+func (s *Store) BrokenLinks() []BrokenLink {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.brokenLinksLocked("")
+}
+
+// brokenLinksLocked returns the broken links pointing at target, or all
+// broken links if target is empty.
+func (s *Store) brokenLinksLocked(target string) []BrokenLink {
+	broken := []BrokenLink{}
+	for _, source := range s.sortedLocked() {
+		for _, link := range ExtractNoteLinks(source.Content) {
+			if target != "" && link.JotID != target {
+				continue
+			}
+			if _, ok := s.jots[link.JotID]; ok {
+				continue
+			}
+			broken = append(broken, BrokenLink{
+				SourceID:    source.ID,
+				SourceTitle: source.Title,
+				TargetID:    link.JotID,
+				Label:       link.Label,
+			})
+		}
+	}
+	return broken
+}
+
+// UnlinkBrokenLinks converts broken links into plain text showing their
+// label. If target is not empty only links to that jot are converted. All
+// affected jots are written together. It returns the changed jots and the
+// number of links converted.
+func (s *Store) UnlinkBrokenLinks(target string) ([]*Jot, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	isBroken := func(id string) bool {
+		if target != "" && id != target {
+			return false
+		}
+		_, ok := s.jots[id]
+		return !ok
+	}
+
+	converted := 0
+	changes := make(map[string]*Jot)
+	for _, source := range s.jots {
+		clone := source.clone()
+		if count := unlinkNoteLinks(clone.Content, isBroken); count > 0 {
+			converted += count
+			changes[source.ID] = clone
+		}
+	}
+	if len(changes) == 0 {
+		return []*Jot{}, 0, nil
+	}
+
+	if err := s.writeLocked(storeChange{jots: changes}); err != nil {
+		return nil, 0, err
+	}
+	changed := make([]*Jot, 0, len(changes))
+	for id := range changes {
+		changed = append(changed, s.jots[id].clone())
+	}
+	return changed, converted, nil
+}
+
+// Revisions returns the revisions of a jot, newest first.
+func (s *Store) Revisions(jotID string) ([]RevisionInfo, error) {
+	return s.revisions.List(jotID)
+}
+
+// Revision returns a single revision of a jot.
+func (s *Store) Revision(jotID, revisionID string) (*Revision, error) {
+	return s.revisions.Get(jotID, revisionID)
+}
+
+// DiffRevisions returns a line diff of the plain text of two revisions of a
+// jot. An empty toID compares against the current jot.
+func (s *Store) DiffRevisions(jotID, fromID, toID string) ([]DiffLine, error) {
+	from, err := s.revisions.Get(jotID, fromID)
+	if err != nil {
+		return nil, err
+	}
+
+	var to *Node
+	if toID == "" {
+		current, ok := s.Get(jotID)
+		if !ok {
+			return nil, fmt.Errorf("jot %s not found", jotID)
+		}
+		to = current.Content
+	} else {
+		revision, err := s.revisions.Get(jotID, toID)
+		if err != nil {
+			return nil, err
+		}
+		to = revision.Content
+	}
+
+	return diffLines(textLines(from.Content), textLines(to)), nil
+}
+
+// RestoreRevision replaces the title and content of a jot with those of a
+// revision. The state before the restore stays available as a revision.
+func (s *Store) RestoreRevision(jotID, revisionID string) (*SaveResult, error) {
+	revision, err := s.revisions.Get(jotID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	current, ok := s.Get(jotID)
+	if !ok {
+		return nil, fmt.Errorf("jot %s not found", jotID)
+	}
+
+	current.Title = revision.Title
+	current.Content = revision.Content
+	current.TextContent = revision.TextContent
+	current.UpdatedAt = time.Now()
+	return s.save(current, false)
+}
+
+// LinkGraph returns the graph of all jots and the links between them.
+func (s *Store) LinkGraph() LinkGraph {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.links.Graph(s.sortedLocked())
+}
+
+// clone returns a deep copy of the jot.
+func (j *Jot) clone() *Jot {
+	clone := *j
+	clone.Content = j.Content.Clone()
+	if j.DeletedAt != nil {
+		deletedAt := *j.DeletedAt
+		clone.DeletedAt = &deletedAt
+	}
+	if j.Source != nil {
+		source := *j.Source
+		clone.Source = &source
+	}
+	return &clone
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating data directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error syncing temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing temp file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
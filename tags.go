@@ -0,0 +1,444 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// hashtagPattern matches a #tag in text. Nested tags are separated by
+// slashes. The character before the # is matched as well, so tags inside
+// words, URLs and HTML entities are not picked up.
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_#&/])#([\p{L}\p{N}_\-]+(?:/[\p{L}\p{N}_\-]+)*)`)
+
+// tagNamePattern matches a valid tag name without the leading #.
+var tagNamePattern = regexp.MustCompile(`^[\p{L}\p{N}_\-]+(?:/[\p{L}\p{N}_\-]+)*$`)
+
+// frontMatterDelimiter opens and closes a front-matter block.
+const frontMatterDelimiter = "---"
+
+// TagInfo is a tag with the number of jots using it or one of its children.
+type TagInfo struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// NormalizeTag lower-cases a tag and strips a leading #. It returns false if
+// the result is not a valid tag name.
+func NormalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if !tagNamePattern.MatchString(tag) || isNumeric(tag) {
+		return "", false
+	}
+	return tag, true
+}
+
+// ExtractTags returns the sorted, normalized tags of a document, from both
+// #hashtags in its text and the tags list of its front matter.
+func ExtractTags(doc *Node) []string {
+	tags := make(map[string]struct{})
+	for _, tag := range frontMatterTags(doc) {
+		tags[tag] = struct{}{}
+	}
+	forEachTextBlock(doc, func(block *Node) {
+		for _, match := range hashtagPattern.FindAllStringSubmatch(blockText(block), -1) {
+			if tag, ok := NormalizeTag(match[1]); ok {
+				tags[tag] = struct{}{}
+			}
+		}
+	})
+	return sortedKeys(tags)
+}
+
+// tagWithParents returns the tag and all of its parent tags, so that
+// "project/alpha" also counts as "project".
+func tagWithParents(tag string) []string {
+	parts := strings.Split(tag, "/")
+	tags := make([]string, len(parts))
+	for i := range parts {
+		tags[i] = strings.Join(parts[:i+1], "/")
+	}
+	return tags
+}
+
+// isNumeric reports whether s consists only of digits, like "#1" in an
+// issue reference, which is not treated as a tag.
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// forEachTextBlock calls fn for every paragraph and heading in doc that is
+// not part of the front matter.
+func forEachTextBlock(doc *Node, fn func(block *Node)) {
+	if doc == nil {
+		return
+	}
+	_, end, hasFrontMatter := frontMatterRange(doc)
+	for i, child := range doc.Content {
+		if hasFrontMatter && i <= end {
+			continue
+		}
+		child.Walk(func(node *Node) bool {
+			if node.Type == "paragraph" || node.Type == "heading" {
+				fn(node)
+				return false
+			}
+			return true
+		})
+	}
+}
+
+// blockText returns the text of a block like ExtractText, but with note
+// links replaced by a space, so their labels never produce tags.
+func blockText(block *Node) string {
+	return inlineText(block, func(*Node) string { return " " })
+}
+
+// isFrontMatterDelimiter reports whether a top-level node opens or closes
+// front matter: a horizontal rule or a paragraph containing only "---".
+func isFrontMatterDelimiter(node *Node) bool {
+	if node.Type == "horizontalRule" {
+		return true
+	}
+	return node.Type == "paragraph" && strings.TrimSpace(blockText(node)) == frontMatterDelimiter
+}
+
+// frontMatterRange returns the indexes of the opening and closing delimiters
+// of the front matter at the start of doc.
+func frontMatterRange(doc *Node) (int, int, bool) {
+	if len(doc.Content) == 0 || !isFrontMatterDelimiter(doc.Content[0]) {
+		return 0, 0, false
+	}
+	for i := 1; i < len(doc.Content); i++ {
+		node := doc.Content[i]
+		if isFrontMatterDelimiter(node) {
+			return 0, i, true
+		}
+		if node.Type != "paragraph" {
+			return 0, 0, false
+		}
+	}
+	return 0, 0, false
+}
+
+// frontMatterTagLines returns the front-matter paragraphs that hold tags:
+// a "tags: a, b" or "tags: [a, b]" line, or "- a" lines following "tags:".
+func frontMatterTagLines(doc *Node) []*Node {
+	start, end, ok := frontMatterRange(doc)
+	if !ok {
+		return nil
+	}
+
+	var lines []*Node
+	inTagList := false
+	for _, node := range doc.Content[start+1 : end] {
+		text := strings.TrimSpace(blockText(node))
+		switch {
+		case strings.HasPrefix(strings.ToLower(text), "tags:"):
+			lines = append(lines, node)
+			inTagList = strings.TrimSpace(text[len("tags:"):]) == ""
+		case inTagList && strings.HasPrefix(text, "- "):
+			lines = append(lines, node)
+		default:
+			inTagList = false
+		}
+	}
+	return lines
+}
+
+// frontMatterItems splits a front-matter tag line into its raw items.
+func frontMatterItems(line string) []string {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(strings.ToLower(line), "tags:") {
+		line = line[len("tags:"):]
+	} else {
+		line = strings.TrimPrefix(line, "- ")
+	}
+	line = strings.Trim(strings.TrimSpace(line), "[]")
+
+	var items []string
+	for _, item := range strings.Split(line, ",") {
+		item = strings.Trim(strings.TrimSpace(item), `"'`)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// frontMatterTags returns the normalized tags listed in the front matter.
+func frontMatterTags(doc *Node) []string {
+	var tags []string
+	for _, line := range frontMatterTagLines(doc) {
+		for _, item := range frontMatterItems(blockText(line)) {
+			if tag, ok := NormalizeTag(item); ok {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// renameTag returns the new name for tag when renaming from to to, and
+// whether tag is from or one of its children.
+func renameTag(tag, from, to string) (string, bool) {
+	tag = strings.ToLower(tag)
+	if tag == from {
+		return to, true
+	}
+	if strings.HasPrefix(tag, from+"/") {
+		return to + tag[len(from):], true
+	}
+	return "", false
+}
+
+// renameTagInDocument renames a tag, and its children, in both the hashtags
+// and the front matter of doc. Both names must be normalized. It returns the
+// number of occurrences renamed.
+func renameTagInDocument(doc *Node, from, to string) int {
+	if doc == nil {
+		return 0
+	}
+
+	renamed := 0
+	for _, line := range frontMatterTagLines(doc) {
+		text := blockText(line)
+		items := frontMatterItems(text)
+		changed := false
+		for i, item := range items {
+			if name, ok := renameTag(strings.TrimPrefix(item, "#"), from, to); ok {
+				items[i] = name
+				changed = true
+				renamed++
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		list := strings.Join(items, ", ")
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(text)), "tags:") {
+			if strings.Contains(text, "[") {
+				list = "[" + list + "]"
+			}
+			list = "tags: " + list
+		} else {
+			list = "- " + list
+		}
+		line.Content = []*Node{{Type: "text", Text: list}}
+	}
+
+	forEachTextBlock(doc, func(block *Node) {
+		renamed += renameTagInBlock(block, from, to)
+	})
+	return renamed
+}
+
+// renameTagInBlock renames the hashtags in a paragraph or heading. Hashtags
+// are found in the text of the whole block, as ExtractTags finds them, so a
+// tag split over differently formatted text is renamed and text that only
+// looks like a tag within one text node is not. The new name takes the
+// formatting of the start of the old one.
+func renameTagInBlock(block *Node, from, to string) int {
+	var text strings.Builder
+	starts := make([]int, len(block.Content))
+	for i, node := range block.Content {
+		starts[i] = text.Len()
+		switch node.Type {
+		case "text":
+			text.WriteString(node.Text)
+		case noteLinkType:
+			text.WriteString(" ")
+		}
+	}
+
+	blockText := text.String()
+	matches := hashtagPattern.FindAllStringSubmatchIndex(blockText, -1)
+	renamed := 0
+	// Matches are replaced from the last, so the offsets of the earlier ones
+	// stay valid.
+	for m := len(matches) - 1; m >= 0; m-- {
+		start, end := matches[m][2], matches[m][3]
+		name, ok := renameTag(blockText[start:end], from, to)
+		if !ok {
+			continue
+		}
+		for i, node := range block.Content {
+			nodeStart := starts[i]
+			if node.Type != "text" || nodeStart >= end || nodeStart+len(node.Text) <= start {
+				continue
+			}
+			replacement := ""
+			if nodeStart <= start {
+				replacement = name
+			}
+			cutStart, cutEnd := max(start, nodeStart)-nodeStart, min(end-nodeStart, len(node.Text))
+			node.Text = node.Text[:cutStart] + replacement + node.Text[cutEnd:]
+		}
+		renamed++
+	}
+	if renamed > 0 {
+		content := block.Content[:0]
+		for _, node := range block.Content {
+			if node.Type != "text" || node.Text != "" {
+				content = append(content, node)
+			}
+		}
+		block.Content = content
+	}
+	return renamed
+}
+
+// TagIndex keeps track of the tags of every jot. It is not safe for
+// concurrent use; the store guards it with its own lock.
+type TagIndex struct {
+	byJot map[string][]string
+	byTag map[string]map[string]struct{}
+}
+
+// NewTagIndex creates an empty tag index.
+func NewTagIndex() *TagIndex {
+	return &TagIndex{
+		byJot: make(map[string][]string),
+		byTag: make(map[string]map[string]struct{}),
+	}
+}
+
+// Update replaces the tags of a jot with the tags in its content.
+func (t *TagIndex) Update(jot *Jot) {
+	t.Remove(jot.ID)
+
+	tags := ExtractTags(jot.Content)
+	if len(tags) == 0 {
+		return
+	}
+	t.byJot[jot.ID] = tags
+	for _, tag := range tags {
+		for _, name := range tagWithParents(tag) {
+			if t.byTag[name] == nil {
+				t.byTag[name] = make(map[string]struct{})
+			}
+			t.byTag[name][jot.ID] = struct{}{}
+		}
+	}
+}
+
+// Remove drops the tags of a jot.
+func (t *TagIndex) Remove(id string) {
+	for _, tag := range t.byJot[id] {
+		for _, name := range tagWithParents(tag) {
+			delete(t.byTag[name], id)
+			if len(t.byTag[name]) == 0 {
+				delete(t.byTag, name)
+			}
+		}
+	}
+	delete(t.byJot, id)
+}
+
+// Tags returns all tags, including parents of nested tags, sorted by name.
+func (t *TagIndex) Tags() []TagInfo {
+	tags := make([]TagInfo, 0, len(t.byTag))
+	for name, jots := range t.byTag {
+		tags = append(tags, TagInfo{Name: name, Count: len(jots)})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags
+}
+
+// Jots returns the ids of the jots with the tag or one of its children.
+func (t *TagIndex) Jots(tag string) []string {
+	return sortedKeys(t.byTag[tag])
+}
+
+// JotTags returns the tags of a single jot.
+func (t *TagIndex) JotTags(id string) []string {
+	return append([]string(nil), t.byJot[id]...)
+}
+
+// Tags returns all tags in use with the number of jots for each.
+func (s *Store) Tags() []TagInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.tags.Tags()
+}
+
+// JotsByTag returns the jots with the tag or one of its children, sorted by
+// updatedAt descending.
+func (s *Store) JotsByTag(tag string) ([]*Jot, error) {
+	name, ok := NormalizeTag(tag)
+	if !ok {
+		return nil, fmt.Errorf("invalid tag: %q", tag)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := make(map[string]*Jot)
+	for _, id := range s.tags.Jots(name) {
+		if jot, ok := s.jots[id]; ok {
+			matches[id] = jot
+		}
+	}
+	jots := sortJots(matches)
+	for i, jot := range jots {
+		jots[i] = jot.clone()
+	}
+	return jots, nil
+}
+
+// RenameTag renames a tag and its children in every jot. Renaming to a tag
+// that already exists merges the two. All affected jots are written
+// together. It returns the changed jots.
+func (s *Store) RenameTag(from, to string) ([]*Jot, error) {
+	oldName, ok := NormalizeTag(from)
+	if !ok {
+		return nil, fmt.Errorf("invalid tag: %q", from)
+	}
+	newName, ok := NormalizeTag(to)
+	if !ok {
+		return nil, fmt.Errorf("invalid tag: %q", to)
+	}
+	if oldName == newName {
+		return []*Jot{}, nil
+	}
+	if strings.HasPrefix(newName, oldName+"/") {
+		return nil, fmt.Errorf("cannot move tag %q into its own child %q", oldName, newName)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := make(map[string]*Jot)
+	for _, id := range s.tags.Jots(oldName) {
+		jot, ok := s.jots[id]
+		if !ok {
+			continue
+		}
+		clone := jot.clone()
+		if renameTagInDocument(clone.Content, oldName, newName) > 0 {
+			changes[id] = clone
+		}
+	}
+	if len(changes) == 0 {
+		return []*Jot{}, nil
+	}
+
+	if err := s.writeLocked(storeChange{jots: changes}); err != nil {
+		return nil, err
+	}
+	changed := make([]*Jot, 0, len(changes))
+	for id := range changes {
+		changed = append(changed, s.jots[id].clone())
+	}
+	return changed, nil
+}
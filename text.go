@@ -0,0 +1,128 @@
+package main
+
+import "strings"
+
+// ExtractText returns the searchable plain text of a document, like
+// extractTextFromTipTap in the frontend but with one line per paragraph or
+// heading instead of everything on one line. Whitespace inside a line is
+// collapsed, note links are replaced by their labels, and the first line of
+// a task item starts with "[x] " or "[ ] " for its checkbox. It is stored as
+// the textContent of every jot that is written, so the text a client sends
+// is never trusted.
+func ExtractText(doc *Node) string {
+	var lines []string
+	var walk func(node *Node, prefix string)
+	walk = func(node *Node, prefix string) {
+		if node == nil {
+			return
+		}
+		switch node.Type {
+		case "paragraph", "heading":
+			if line := strings.Join(strings.Fields(inlineText(node, noteLinkLabel)), " "); line != "" || prefix != "" {
+				lines = append(lines, strings.TrimSpace(prefix+line))
+			}
+			return
+		case "taskItem":
+			checkbox := "[ ] "
+			if node.BoolAttr("checked") {
+				checkbox = "[x] "
+			}
+			if len(node.Content) == 0 {
+				lines = append(lines, strings.TrimSpace(checkbox))
+			}
+			for i, child := range node.Content {
+				if i == 0 {
+					walk(child, checkbox)
+				} else {
+					walk(child, "")
+				}
+			}
+			return
+		}
+		for _, child := range node.Content {
+			walk(child, prefix)
+		}
+	}
+	walk(doc, "")
+	return strings.Join(lines, "\n")
+}
+
+// textLines returns the lines of the text of a document, for diffing
+// revisions.
+func textLines(doc *Node) []string {
+	text := ExtractText(doc)
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// inlineText returns the text of the inline content of a block, with note
+// links replaced by linkText.
+func inlineText(block *Node, linkText func(link *Node) string) string {
+	var text strings.Builder
+	block.Walk(func(node *Node) bool {
+		switch node.Type {
+		case "text":
+			text.WriteString(node.Text)
+		case noteLinkType:
+			text.WriteString(linkText(node))
+		}
+		return true
+	})
+	return text.String()
+}
+
+// noteLinkLabel returns the label a note link shows.
+func noteLinkLabel(link *Node) string {
+	return link.StringAttr("label")
+}
+
+// refreshText recomputes the text of a jot from its content. A locked jot
+// keeps its text sealed with its content, so it is left alone.
+func refreshText(jot *Jot) {
+	if jot != nil && jot.Lock == nil {
+		jot.TextContent = ExtractText(jot.Content)
+	}
+}
+
+// ReindexAll recomputes the text of every jot, including those in the
+// trash, and rebuilds the link and tag indexes. It is for when the rules for
+// extracting text or building the indexes change. It returns copies of the
+// jots whose text changed.
+func (s *Store) ReindexAll() ([]*Jot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change := storeChange{jots: make(map[string]*Jot), trash: make(map[string]*Jot)}
+	var changed []*Jot
+	for id, jot := range s.jots {
+		clone := jot.clone()
+		refreshText(clone)
+		if clone.TextContent != jot.TextContent {
+			change.jots[id] = clone
+			changed = append(changed, clone.clone())
+		}
+	}
+	for id, jot := range s.trash {
+		clone := jot.clone()
+		refreshText(clone)
+		if clone.TextContent != jot.TextContent {
+			change.trash[id] = clone
+		}
+	}
+	if len(change.jots) > 0 || len(change.trash) > 0 {
+		if err := s.writeLocked(change); err != nil {
+			return nil, err
+		}
+	} else if s.locked {
+		return nil, errStoreLocked
+	}
+
+	s.links = NewLinkIndex()
+	s.tags = NewTagIndex()
+	for id, jot := range s.jots {
+		s.indexLocked(id, jot)
+	}
+	return changed, nil
+}
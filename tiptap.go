@@ -0,0 +1,106 @@
+package main
+
+// Node is a single node of a TipTap (ProseMirror) JSON document, mirroring
+// the JSONContent type used by the editor in the frontend.
+type Node struct {
+	Type    string                 `json:"type"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+	Content []*Node                `json:"content,omitempty"`
+	Marks   []Mark                 `json:"marks,omitempty"`
+	Text    string                 `json:"text,omitempty"`
+}
+
+// Mark is an inline mark (bold, italic, ...) applied to a text node.
+type Mark struct {
+	Type  string                 `json:"type"`
+	Attrs map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// NewDocument returns an empty TipTap document.
+func NewDocument() *Node {
+	return &Node{Type: "doc", Content: []*Node{}}
+}
+
+// Walk calls fn for the node and all of its descendants in document order.
+// Returning false from fn skips the children of that node.
+func (n *Node) Walk(fn func(node *Node) bool) {
+	if n == nil {
+		return
+	}
+	if !fn(n) {
+		return
+	}
+	for _, child := range n.Content {
+		child.Walk(fn)
+	}
+}
+
+// StringAttr returns the attribute as a string, or "" if it is missing or
+// not a string.
+func (n *Node) StringAttr(name string) string {
+	if n == nil || n.Attrs == nil {
+		return ""
+	}
+	value, _ := n.Attrs[name].(string)
+	return value
+}
+
+// Clone returns a deep copy of the node.
+func (n *Node) Clone() *Node {
+	if n == nil {
+		return nil
+	}
+	clone := &Node{
+		Type: n.Type,
+		Text: n.Text,
+	}
+	if n.Attrs != nil {
+		clone.Attrs = make(map[string]interface{}, len(n.Attrs))
+		for key, value := range n.Attrs {
+			clone.Attrs[key] = value
+		}
+	}
+	if n.Content != nil {
+		clone.Content = make([]*Node, len(n.Content))
+		for i, child := range n.Content {
+			clone.Content[i] = child.Clone()
+		}
+	}
+	if n.Marks != nil {
+		clone.Marks = make([]Mark, len(n.Marks))
+		for i, mark := range n.Marks {
+			clone.Marks[i] = Mark{Type: mark.Type}
+			if mark.Attrs != nil {
+				clone.Marks[i].Attrs = make(map[string]interface{}, len(mark.Attrs))
+				for key, value := range mark.Attrs {
+					clone.Marks[i].Attrs[key] = value
+				}
+			}
+		}
+	}
+	return clone
+}
+
+// IntAttr returns a numeric attribute as an int. JSON numbers decode as
+// float64, so both are accepted.
+func (n *Node) IntAttr(name string, fallback int) int {
+	if n == nil || n.Attrs == nil {
+		return fallback
+	}
+	switch value := n.Attrs[name].(type) {
+	case float64:
+		return int(value)
+	case int:
+		return value
+	}
+	return fallback
+}
+
+// BoolAttr returns a boolean attribute, or false if it is missing.
+func (n *Node) BoolAttr(name string) bool {
+	if n == nil || n.Attrs == nil {
+		return false
+	}
+	value, _ := n.Attrs[name].(bool)
+	return value
+}
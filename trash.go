@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// trashPurgeInterval is how often the trash is checked for expired jots.
+const trashPurgeInterval = time.Hour
+
+// Trash returns the jots in the trash, most recently deleted first.
+func (s *Store) Trash() []*Jot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jots := sortJots(s.trash)
+	sort.SliceStable(jots, func(i, j int) bool {
+		return jots[i].DeletedAt.After(*jots[j].DeletedAt)
+	})
+	for i, jot := range jots {
+		jots[i] = jot.clone()
+	}
+	return jots
+}
+
+// RestoreFromTrash moves a jot from the trash back into the store. Its link
+// labels are refreshed, since the linked jots may have been renamed while it
+// was in the trash.
+func (s *Store) RestoreFromTrash(id string) (*Jot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trashed, ok := s.trash[id]
+	if !ok {
+		return nil, fmt.Errorf("jot %s is not in the trash", id)
+	}
+	if _, exists := s.jots[id]; exists {
+		return nil, fmt.Errorf("jot %s already exists", id)
+	}
+
+	restored := trashed.clone()
+	restored.DeletedAt = nil
+	if _, ok := s.folders[restored.FolderID]; !ok {
+		restored.FolderID = ""
+	}
+	relabelNoteLinks(restored.Content, s.titleLocked)
+	if err := s.writeLocked(storeChange{
+		jots:  map[string]*Jot{id: restored},
+		trash: map[string]*Jot{id: nil},
+	}); err != nil {
+		return nil, err
+	}
+	return restored.clone(), nil
+}
+
+// EmptyTrash permanently deletes every jot in the trash and returns the
+// number of jots deleted.
+func (s *Store) EmptyTrash() (int, error) {
+	return s.purgeTrash(func(*Jot) bool { return true })
+}
+
+// PurgeTrash permanently deletes the jots that have been in the trash for
+// longer than maxAge and returns the number of jots deleted.
+func (s *Store) PurgeTrash(maxAge time.Duration) (int, error) {
+	cutoff := time.Now().Add(-maxAge)
+	return s.purgeTrash(func(jot *Jot) bool {
+		return jot.DeletedAt.Before(cutoff)
+	})
+}
+
+// purgeTrash permanently deletes the jots in the trash that match, along
+// with their revisions.
+func (s *Store) purgeTrash(matches func(jot *Jot) bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := make(map[string]*Jot)
+	for id, jot := range s.trash {
+		if matches(jot) {
+			purged[id] = nil
+		}
+	}
+	if len(purged) == 0 {
+		return 0, nil
+	}
+
+	if err := s.writeLocked(storeChange{trash: purged}); err != nil {
+		return 0, err
+	}
+	for id := range purged {
+		if err := s.revisions.Remove(id); err != nil {
+			fmt.Printf("Error removing revisions of %s: %v\n", id, err)
+		}
+	}
+	return len(purged), nil
+}
+
+// runTrashPurge purges expired jots from the trash on start and then
+// periodically, until ctx is done. The retention is read from the settings
+// on every run, so changes apply without a restart.
+func runTrashPurge(ctx context.Context, store *Store, settings *SettingsStore) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if days := settings.Get().TrashRetentionDays; days > 0 {
+			purged, err := store.PurgeTrash(time.Duration(days) * 24 * time.Hour)
+			if err != nil {
+				fmt.Printf("Error purging trash: %v\n", err)
+			} else if purged > 0 {
+				fmt.Printf("Purged %d jots from the trash\n", purged)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}